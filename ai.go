@@ -2,13 +2,18 @@ package main
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
 	"net/http"
+	"path/filepath"
 	"strings"
+	"time"
 )
 
+const aiRequestTimeout = 5 * time.Minute
+
 type AIParsedTrack struct {
 	OriginalFilename string `json:"original_filename"`
 	Artist           string `json:"artist"`
@@ -76,18 +81,25 @@ Filenames:
 		return nil, err
 	}
 
-	url := "https://generativelanguage.googleapis.com/v1beta/models/gemini-2.5-flash-lite:generateContent?key=" + apiKey
-	req, err := http.NewRequest("POST", url, bytes.NewBuffer(jsonData))
+	url := "https://generativelanguage.googleapis.com/v1beta/models/gemini-2.5-flash-lite:generateContent"
+	jobCtx, done := a.startJob("ai-parse")
+	defer done()
+	// Long filename lists take the model a while; allow more than the
+	// default request timeout.
+	ctx, cancel := context.WithTimeout(jobCtx, aiRequestTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewBuffer(jsonData))
 	if err != nil {
 		return nil, err
 	}
 
 	req.Header.Set("Content-Type", "application/json")
+	// The key goes in a header so it never appears in a logged or returned URL.
+	req.Header.Set("x-goog-api-key", apiKey)
 
-	client := &http.Client{}
-	resp, err := client.Do(req)
+	resp, err := sharedHTTPClient().Do(req)
 	if err != nil {
-		return nil, err
+		return nil, cancelledErr(jobCtx, err)
 	}
 	defer resp.Body.Close()
 
@@ -98,7 +110,7 @@ Filenames:
 
 	var geminiResp GeminiResponse
 	if err := json.NewDecoder(resp.Body).Decode(&geminiResp); err != nil {
-		return nil, err
+		return nil, cancelledErr(jobCtx, err)
 	}
 
 	if len(geminiResp.Candidates) == 0 || len(geminiResp.Candidates[0].Content.Parts) == 0 {
@@ -119,3 +131,42 @@ Filenames:
 
 	return result.Tracks, nil
 }
+
+// GenerateAIRenames turns AI parse results into proposed names using the same
+// rename template as the other methods. Files the AI did not return are
+// left unchanged.
+func (a *App) GenerateAIRenames(localTracks []LocalTrack, parsed []AIParsedTrack, pattern string) ([]MatchedTrack, error) {
+	tmpl, err := resolveRenameTemplate(pattern)
+	if err != nil {
+		return nil, err
+	}
+
+	byName := make(map[string]AIParsedTrack, len(parsed))
+	for _, p := range parsed {
+		byName[p.OriginalFilename] = p
+	}
+
+	matchedTracks := make([]MatchedTrack, 0, len(localTracks))
+	for _, local := range localTracks {
+		track := MatchedTrack{
+			LocalPath:       local.Path,
+			OriginalName:    local.OriginalName,
+			ProposedNewName: local.OriginalName,
+			Confidence:      0.1,
+			Status:          statusAIFailed,
+		}
+		if p, ok := byName[local.OriginalName]; ok {
+			fields := localTemplateFields(local)
+			fields["artist"] = strings.TrimSpace(p.Artist)
+			fields["title"] = strings.TrimSpace(p.Title)
+			if num := strings.TrimSpace(p.TrackNumber); num != "" {
+				fields["track"] = num
+			}
+			track.ProposedNewName = tmpl.Render(fields, filepath.Ext(local.OriginalName))
+			track.Confidence = 0.9
+			track.Status = statusAIParsed
+		}
+		matchedTracks = append(matchedTracks, track)
+	}
+	return matchedTracks, nil
+}
@@ -2,26 +2,28 @@ package main
 
 import (
 	"context"
-	"encoding/json"
 	"fmt"
 	"log"
-	"net/http"
-	"os"
+	"math"
 	"path/filepath"
 	"regexp"
 	"strconv"
 	"strings"
+	"sync"
 
-	"github.com/PuerkitoBio/goquery"
 	"github.com/adrg/strutil"
 	"github.com/adrg/strutil/metrics"
-	"github.com/dhowden/tag"
 	"github.com/wailsapp/wails/v2/pkg/runtime"
 )
 
 // App struct
 type App struct {
-	ctx context.Context
+	ctx       context.Context
+	journalMu sync.Mutex
+
+	jobsMu sync.Mutex
+	jobs   map[string]context.CancelFunc
+	jobSeq uint64
 }
 
 // NewApp creates a new App application struct
@@ -36,49 +38,89 @@ func (a *App) startup(ctx context.Context) {
 // --- Struct Definitions ---
 
 type LocalTrack struct {
-	Path         string `json:"path"`
-	OriginalName string `json:"originalName"`
-	TagArtist    string `json:"tagArtist"`
-	TagTitle     string `json:"tagTitle"`
-}
+	Path           string  `json:"path"`
+	OriginalName   string  `json:"originalName"`
+	TagArtist      string  `json:"tagArtist"`
+	TagTitle       string  `json:"tagTitle"`
+	TagAlbum       string  `json:"tagAlbum"`
+	TagAlbumArtist string  `json:"tagAlbumArtist"`
+	TagTrack       int     `json:"tagTrack"`
+	TagTrackTotal  int     `json:"tagTrackTotal"`
+	TagDisc        int     `json:"tagDisc"`
+	TagDiscTotal   int     `json:"tagDiscTotal"`
+	TagYear        int     `json:"tagYear"`
+	TagGenre       string  `json:"tagGenre"`
+	TagComposer    string  `json:"tagComposer"`
+	HasPicture     bool    `json:"hasPicture"`
+	PictureMIME    string  `json:"pictureMime"`
+	Group          string  `json:"group"`
+	Format         string  `json:"format"`
+	Duration       float64 `json:"duration"`
+}
+
+// Row statuses that say how a proposed name was found. A store match
+// reports "<Source> Match"; the frontend lists freshly scanned files as
+// "Original".
+const (
+	statusMatched   = "Matched"
+	statusNoMatch   = "No Match"
+	statusUnmatched = "Unmatched"
+	statusAIParsed  = "AI Parsed"
+	statusAIFailed  = "AI Failed"
+)
 
-type BandcampAlbum struct {
-	Artist    string          `json:"artist"`
-	TrackInfo []BandcampTrack `json:"trackinfo"`
-	Current   CurrentInfo     `json:"current"`
+type MatchedTrack struct {
+	LocalPath       string            `json:"localPath"`
+	OriginalName    string            `json:"originalName"`
+	ProposedNewName string            `json:"proposedNewName"`
+	Confidence      float64           `json:"confidence"`
+	Status          string            `json:"status"`
+	Warning         string            `json:"warning"`
+	Explanation     *MatchExplanation `json:"explanation,omitempty"`
 }
 
-type CurrentInfo struct {
-	Title string `json:"title"`
+// MatchResult is what matching a folder against a release produces: a row for
+// every local file (matched or not) and the release tracks that had no file.
+type MatchResult struct {
+	Tracks  []MatchedTrack `json:"tracks"`
+	Missing []MissingTrack `json:"missing"`
 }
 
-type BandcampTrack struct {
-	Title    string  `json:"title"`
-	Artist   *string `json:"artist"`
-	TrackNum int     `json:"track_num"`
-}
-
-type MatchedTrack struct {
-	LocalPath       string  `json:"localPath"`
-	OriginalName    string  `json:"originalName"`
-	ProposedNewName string  `json:"proposedNewName"`
-	Confidence      float64 `json:"confidence"`
-	Status          string  `json:"status"`
+type MissingTrack struct {
+	Group    string `json:"group"`
+	DiscNum  int    `json:"discNum"`
+	TrackNum int    `json:"trackNum"`
+	Artist   string `json:"artist"`
+	Title    string `json:"title"`
 }
 
 type AlbumData struct {
-	Artist string
-	Title  string
-	Tracks []AlbumTrack
-	Source string
+	Artist        string       `json:"artist"`
+	Title         string       `json:"title"`
+	Label         string       `json:"label"`
+	CatalogNumber string       `json:"catalogNumber"`
+	Year          int          `json:"year"`
+	ReleaseDate   string       `json:"releaseDate"`
+	DiscTotal     int          `json:"discTotal"`
+	Tracks        []AlbumTrack `json:"tracks"`
+	Source        string       `json:"source"`
 }
 
 type AlbumTrack struct {
-	Title            string
-	Artist           string
-	TrackNum         int
-	TrackNumExplicit bool
-	TrackID          int
+	Title            string  `json:"title"`
+	Artist           string  `json:"artist"`
+	TrackNum         int     `json:"trackNum"`
+	TrackNumExplicit bool    `json:"trackNumExplicit"`
+	TrackID          int     `json:"trackId"`
+	Duration         float64 `json:"duration"`
+	// DiscNum is the medium the track is on; 0 when the store has no disc
+	// structure.
+	DiscNum int `json:"discNum"`
+	// Position is the store's own position label when it is not a plain
+	// number, e.g. "A1" on vinyl.
+	Position string `json:"position"`
+	Credits  string `json:"credits"`
+	ISRC     string `json:"isrc"`
 }
 
 type templateCandidate struct {
@@ -95,6 +137,7 @@ var (
 	reBPM            = regexp.MustCompile(`(?i)(\d{2,3})\s*[-_ ]*bpm`)
 	reTrackPrefix    = regexp.MustCompile(`^\s*(\d{1,2})[.\s_-]+(.+)$`)
 	reTrackOnly      = regexp.MustCompile(`^\s*(\d{1,2})\b`)
+	reDiscTrack      = regexp.MustCompile(`^\s*(\d{1,2})[-.](\d{1,3})\b`)
 	reDigitsOnly     = regexp.MustCompile(`^\d{1,3}$`)
 	reLabelKeywords  = regexp.MustCompile(`(?i)\b(records?|recordings|music|label|netlabel|rec|recs)\b`)
 	reMultiDash      = regexp.MustCompile(`--+`)
@@ -363,27 +406,6 @@ func chooseBestCandidate(candidates ...templateCandidate) templateCandidate {
 	return best
 }
 
-func appendBPMIfMissing(title string, bpm string, style string) string {
-	if bpm == "" {
-		return title
-	}
-	lower := strings.ToLower(title)
-	if strings.Contains(lower, "bpm") {
-		return title
-	}
-	return strings.TrimSpace(title) + " " + formatBPM(bpm, style)
-}
-
-func formatTrackPrefix(track string) string {
-	if track == "" {
-		return ""
-	}
-	if n, err := strconv.Atoi(track); err == nil {
-		return fmt.Sprintf("%02d. ", n)
-	}
-	return track + ". "
-}
-
 func cleanTrackTitle(title string, trackNum int) string {
 	cleaned := title
 	prefixes := []string{
@@ -493,6 +515,15 @@ func buildTemplateCandidate(localTrack LocalTrack) templateCandidate {
 	fileCand.BPM = bpm
 	fileCand.BPMStyle = bpmStyle
 
+	// Filenames without a track prefix fall back to the tagged track number.
+	tagTrack := ""
+	if localTrack.TagTrack > 0 {
+		tagTrack = strconv.Itoa(localTrack.TagTrack)
+	}
+	if fileCand.Track == "" {
+		fileCand.Track = tagTrack
+	}
+
 	tagArtist := strings.TrimSpace(localTrack.TagArtist)
 	tagTitle := strings.TrimSpace(localTrack.TagTitle)
 	if tagArtist != "" || tagTitle != "" {
@@ -500,6 +531,7 @@ func buildTemplateCandidate(localTrack LocalTrack) templateCandidate {
 			tagCand := templateCandidate{
 				Artist:     tagArtist,
 				Title:      tagTitle,
+				Track:      tagTrack,
 				BPM:        bpm,
 				BPMStyle:   bpmStyle,
 				Confidence: 0.85,
@@ -519,7 +551,7 @@ func buildTemplateCandidate(localTrack LocalTrack) templateCandidate {
 
 // --- Core Functions ---
 
-func (a *App) SelectFolder() ([]LocalTrack, error) {
+func (a *App) SelectFolder() (*FolderScan, error) {
 	dirPath, err := runtime.OpenDirectoryDialog(a.ctx, runtime.OpenDialogOptions{
 		Title: "Select Album Folder",
 	})
@@ -527,48 +559,21 @@ func (a *App) SelectFolder() ([]LocalTrack, error) {
 		return nil, err
 	}
 	if dirPath == "" {
-		return []LocalTrack{}, nil
+		return &FolderScan{Groups: []AlbumGroup{}, Skipped: []SkippedFile{}}, nil
 	}
+	ctx, done := a.startJob("scan")
+	defer done()
+	scan, err := scanFolder(ctx, dirPath, false)
+	return scan, cancelledErr(ctx, err)
+}
 
-	files, err := os.ReadDir(dirPath)
+func (a *App) GenerateTemplateRenames(localTracks []LocalTrack, format string) ([]MatchedTrack, error) {
+	tmpl, err := resolveRenameTemplate(format)
 	if err != nil {
 		return nil, err
 	}
 
-	var localTracks []LocalTrack
-	validExts := map[string]bool{".flac": true, ".mp3": true, ".wav": true, ".aiff": true}
-
-	for _, file := range files {
-		if file.IsDir() {
-			continue
-		}
-		ext := strings.ToLower(filepath.Ext(file.Name()))
-		if validExts[ext] {
-			track := LocalTrack{
-				Path:         filepath.Join(dirPath, file.Name()),
-				OriginalName: file.Name(),
-			}
-
-			// Try to read tags
-			f, err := os.Open(track.Path)
-			if err == nil {
-				m, err := tag.ReadFrom(f)
-				if err == nil {
-					track.TagArtist = m.Artist()
-					track.TagTitle = m.Title()
-				}
-				f.Close()
-			}
-
-			localTracks = append(localTracks, track)
-		}
-	}
-	return localTracks, nil
-}
-
-func (a *App) GenerateTemplateRenames(localTracks []LocalTrack, format string) ([]MatchedTrack, error) {
 	var matchedTracks []MatchedTrack
-
 	for _, localTrack := range localTracks {
 		cand := buildTemplateCandidate(localTrack)
 		track := MatchedTrack{
@@ -576,49 +581,51 @@ func (a *App) GenerateTemplateRenames(localTracks []LocalTrack, format string) (
 			OriginalName:    localTrack.OriginalName,
 			ProposedNewName: localTrack.OriginalName,
 			Confidence:      0,
-			Status:          "No Match",
+			Status:          statusNoMatch,
 		}
 
 		if cand.Artist != "" && cand.Title != "" {
-			title := appendBPMIfMissing(cand.Title, cand.BPM, cand.BPMStyle)
-			var base string
-			if format == "Track. Title" {
-				base = title
-			} else {
-				if cand.Artist != "" {
-					base = fmt.Sprintf("%s - %s", cand.Artist, title)
-				} else {
-					base = title
-				}
-			}
-			prefix := formatTrackPrefix(cand.Track)
-			proposed := strings.TrimSpace(prefix + base)
-			proposed = reSpaces.ReplaceAllString(proposed, " ")
-			ext := filepath.Ext(localTrack.OriginalName)
-			if ext != "" {
-				proposed += ext
-			}
-			track.ProposedNewName = proposed
+			fields := localTemplateFields(localTrack)
+			fields["artist"] = cand.Artist
+			fields["title"] = cand.Title
+			fields["track"] = cand.Track
+			fields["bpm"] = cand.BPM
+			if cand.BPM != "" && !strings.Contains(strings.ToLower(cand.Title), "bpm") {
+				fields["bpmtag"] = formatBPM(cand.BPM, cand.BPMStyle)
+			}
+			track.ProposedNewName = tmpl.Render(fields, filepath.Ext(localTrack.OriginalName))
 			track.Confidence = cand.Confidence
-			track.Status = "Matched"
+			track.Status = statusMatched
 		}
 		matchedTracks = append(matchedTracks, track)
 	}
 	return matchedTracks, nil
 }
 
-func (a *App) FetchAndMatchTracks(url string, localTracks []LocalTrack) ([]MatchedTrack, error) {
-	album, err := a.fetchAlbumData(url)
+func (a *App) FetchAndMatchTracks(url string, localTracks []LocalTrack, pattern string) (*MatchResult, error) {
+	ctx, done := a.startJob("fetch")
+	defer done()
+	return a.fetchAndMatch(ctx, url, localTracks, pattern)
+}
+
+func (a *App) fetchAndMatch(ctx context.Context, url string, localTracks []LocalTrack, pattern string) (*MatchResult, error) {
+	tmpl, err := resolveRenameTemplate(pattern)
 	if err != nil {
-		return nil, fmt.Errorf("failed to fetch or parse album data: %w", err)
+		return nil, err
 	}
+	album, err := a.fetchAlbumData(ctx, url)
+	if err != nil {
+		return nil, cancelledErr(ctx, fmt.Errorf("failed to fetch or parse album data: %w", err))
+	}
+	return matchAlbum(album, url, album.Source, localTracks, tmpl), nil
+}
 
-	var matchedTracks []MatchedTrack
-	sm := metrics.NewSorensenDice()
-	sm.CaseSensitive = false
-
-	availableLocalTracks := make([]LocalTrack, len(localTracks))
-	copy(availableLocalTracks, localTracks)
+// matchAlbum pairs local files with the album's tracks and names them with
+// tmpl. url is only used to recognise various-artists releases. source is
+// the store the track numbering comes from, which decides the store-specific
+// rules; album.Source is only the label shown to the user.
+func matchAlbum(album *AlbumData, url string, source string, localTracks []LocalTrack, tmpl *renameTemplate) *MatchResult {
+	result := &MatchResult{Tracks: []MatchedTrack{}, Missing: []MissingTrack{}}
 
 	log.Printf("Album URL: %s", url)
 	log.Printf("Album Artist: %s", album.Artist)
@@ -626,1006 +633,361 @@ func (a *App) FetchAndMatchTracks(url string, localTracks []LocalTrack) ([]Match
 	isVA := strings.Contains(lowerAlbumArtist, "various") || strings.Contains(lowerAlbumArtist, "v.a.") || strings.Contains(lowerAlbumArtist, "va ") || strings.Contains(lowerAlbumArtist, "various artists") || strings.Contains(url, "/va-")
 	log.Printf("Is VA Album (calculated): %t", isVA)
 
-	for _, albumTrack := range album.Tracks {
-		log.Printf("Processing Album Track: %s (Num: %d)", albumTrack.Title, albumTrack.TrackNum)
-
-		if len(availableLocalTracks) == 0 {
-			break
-		}
-
-		var bestMatchIndex = -1
-		var bestMatchRating float64 = -1.0
-
-		candidateIndexes := make([]int, len(availableLocalTracks))
-		for i := range availableLocalTracks {
-			candidateIndexes[i] = i
-		}
-
-		for _, i := range candidateIndexes {
-			local := availableLocalTracks[i]
-			// **FIXED**: Use the full original name (without extension) for comparison.
-			localNameToCompare := strings.TrimSuffix(local.OriginalName, filepath.Ext(local.OriginalName))
-			normalizedLocal := normalizeForMatch(localNameToCompare)
-
-			// Calculate similarity based on filename
-			ratingFilename := 0.0
-			normalizedTitle := normalizeForMatch(albumTrack.Title)
-			if normalizedTitle != "" && hasTokenOverlap(normalizedTitle, normalizedLocal) {
-				ratingFilename = strutil.Similarity(normalizedTitle, normalizedLocal, sm)
-			}
-			normalizedFull := ""
-			if strings.TrimSpace(albumTrack.Artist) != "" {
-				normalizedFull = normalizeForMatch(albumTrack.Artist + " - " + albumTrack.Title)
-			} else if album.Artist != "" {
-				normalizedFull = normalizeForMatch(album.Artist + " - " + albumTrack.Title)
-			}
-			if normalizedFull != "" && hasTokenOverlap(normalizedFull, normalizedLocal) {
-				if score := strutil.Similarity(normalizedFull, normalizedLocal, sm); score > ratingFilename {
-					ratingFilename = score
-				}
-			}
-
-			// Calculate similarity based on tags (if available)
-			ratingTags := 0.0
-			if local.TagTitle != "" {
-				tagBase := normalizeForMatch(local.TagTitle)
-				if tagBase != "" && normalizedTitle != "" && hasTokenOverlap(normalizedTitle, tagBase) {
-					ratingTags = strutil.Similarity(normalizedTitle, tagBase, sm)
-				}
-				if local.TagArtist != "" && normalizedFull != "" {
-					tagFull := normalizeForMatch(local.TagArtist + " - " + local.TagTitle)
-					if tagFull != "" && hasTokenOverlap(normalizedFull, tagFull) {
-						if score := strutil.Similarity(normalizedFull, tagFull, sm); score > ratingTags {
-							ratingTags = score
-						}
-					}
-				}
-			}
-
-			// Use the higher of the two ratings
-			rating := ratingFilename
-			if ratingTags > rating {
-				rating = ratingTags
-				log.Printf("  Higher match found using tags for '%s': %f", local.OriginalName, rating)
-			}
-
-			log.Printf("  Comparing Album '%s' with Local '%s' (Tags: '%s' - '%s')", albumTrack.Title, localNameToCompare, local.TagArtist, local.TagTitle)
-			log.Printf("  Similarity Rating: %f", rating)
-
-			if albumTrack.TrackNumExplicit && albumTrack.TrackNum > 0 {
-				localNum := extractTrackNumber(localNameToCompare)
-				if localNum != "" {
-					if n, err := strconv.Atoi(localNum); err == nil && n == albumTrack.TrackNum {
-						if rating < 0.9 {
-							rating += 0.12
-							if rating > 1.0 {
-								rating = 1.0
-							}
-						}
-					}
-				}
-			}
-
-			if rating > bestMatchRating {
-				bestMatchRating = rating
-				bestMatchIndex = i
-			}
-		}
-
-		minConfidence := 0.0
-		if strings.EqualFold(album.Source, "Beatport") {
-			minConfidence = 0.25
-		}
-		if bestMatchIndex != -1 && bestMatchRating >= minConfidence {
-			matchedLocalTrack := availableLocalTracks[bestMatchIndex]
-			ext := filepath.Ext(matchedLocalTrack.OriginalName)
-
-			localTrackNum := extractTrackNumber(strings.TrimSuffix(matchedLocalTrack.OriginalName, ext))
-			trackNumForName := albumTrack.TrackNum
-			if localTrackNum != "" {
-				if n, err := strconv.Atoi(localTrackNum); err == nil {
-					if strings.EqualFold(album.Source, "Beatport") && albumTrack.TrackNumExplicit && albumTrack.TrackNum != n {
-						trackNumForName = n
-					} else if !albumTrack.TrackNumExplicit {
-						trackNumForName = n
-					}
-				}
-			}
-
-			trackNumForClean := 0
-			if albumTrack.TrackNumExplicit {
-				trackNumForClean = albumTrack.TrackNum
-			}
-
-			// 1. Clean the title to handle cases where the track number is duplicated by Bandcamp
-			cleanedTitle := albumTrack.Title
-			if trackNumForClean > 0 {
-				cleanedTitle = cleanTrackTitle(albumTrack.Title, trackNumForClean)
-			}
-
-			// 2. Now apply the logic to construct the name
-			var proposedName string
-			albumArtistFromTitle := strings.TrimSpace(album.Artist)
-			if albumArtistFromTitle == "" {
-				albumTitleParts := strings.SplitN(album.Title, " - ", 2)
-				if len(albumTitleParts) > 1 {
-					albumArtistFromTitle = albumTitleParts[0]
-				}
-			}
-
-			// Use the cleaned title for the check
-			if strings.Contains(cleanedTitle, "-") {
-				// Title is likely "Artist - Title", so use it as is.
-				proposedName = fmt.Sprintf("%02d. %s%s", trackNumForName, cleanedTitle, ext)
-			} else if strings.TrimSpace(albumTrack.Artist) != "" {
-				// Track contains artist info, so use it.
-				proposedName = fmt.Sprintf("%02d. %s - %s%s", trackNumForName, strings.TrimSpace(albumTrack.Artist), cleanedTitle, ext)
-			} else if albumArtistFromTitle != "" {
-				// Title does not contain artist, so prepend the artist from the album title.
-				proposedName = fmt.Sprintf("%02d. %s - %s%s", trackNumForName, albumArtistFromTitle, cleanedTitle, ext)
-			} else {
-				// Fallback: can't find artist anywhere, just use the cleaned title.
-				proposedName = fmt.Sprintf("%02d. %s%s", trackNumForName, cleanedTitle, ext)
-			}
-
-			matchedTracks = append(matchedTracks, MatchedTrack{
-				LocalPath:       matchedLocalTrack.Path,
-				OriginalName:    matchedLocalTrack.OriginalName,
-				ProposedNewName: proposedName,
-				Confidence:      bestMatchRating,
-				Status:          fmt.Sprintf("%s Match", album.Source),
+	minConfidence := minMatchScore
+	if strings.EqualFold(source, "Beatport") {
+		minConfidence = 0.25
+	}
+
+	// Score every album track against every local file and pick the pairing
+	// with the best total score. Matching one track at a time let an early,
+	// weak match take the file a later track matched almost perfectly.
+	scores, explanations := scoreAlbum(album, localTracks)
+	assignment := solveAssignment(scores, minConfidence)
+
+	matchedLocal := make([]bool, len(localTracks))
+	for i, albumTrack := range album.Tracks {
+		j := assignment[i]
+		if j < 0 {
+			result.Missing = append(result.Missing, MissingTrack{
+				DiscNum:  albumTrack.DiscNum,
+				TrackNum: albumTrack.TrackNum,
+				Artist:   albumTrack.Artist,
+				Title:    albumTrack.Title,
 			})
-
-			availableLocalTracks = append(availableLocalTracks[:bestMatchIndex], availableLocalTracks[bestMatchIndex+1:]...)
-		}
-	}
-
-	return matchedTracks, nil
-}
-
-func (a *App) RenameMatchedTracks(tracks []MatchedTrack) (string, error) {
-	renamedCount := 0
-	for _, track := range tracks {
-		if track.OriginalName == track.ProposedNewName {
-			continue
-		}
-
-		newPath := filepath.Join(filepath.Dir(track.LocalPath), track.ProposedNewName)
-
-		if _, err := os.Stat(newPath); !os.IsNotExist(err) {
-			log.Printf("Skipping rename for %s: target file %s already exists", track.OriginalName, track.ProposedNewName)
-			continue
-		}
-
-		if err := os.Rename(track.LocalPath, newPath); err != nil {
-			log.Printf("Error renaming %s to %s: %v", track.LocalPath, newPath, err)
 			continue
 		}
-		renamedCount++
-	}
-	return fmt.Sprintf("Successfully renamed %d track(s).", renamedCount), nil
-}
-
-func (a *App) fetchAlbumData(url string) (*AlbumData, error) {
-	lower := strings.ToLower(url)
-	if strings.Contains(lower, "beatport.com") {
-		return a.fetchBeatportData(url)
+		matchedLocal[j] = true
+		matchedLocalTrack := localTracks[j]
+		ext := filepath.Ext(matchedLocalTrack.OriginalName)
+
+		trackNumForName := albumTrack.TrackNum
+		if n := localTrackNumber(matchedLocalTrack, album); n > 0 {
+			if strings.EqualFold(source, "Beatport") && albumTrack.TrackNumExplicit && albumTrack.TrackNum != n {
+				trackNumForName = n
+			} else if !albumTrack.TrackNumExplicit {
+				trackNumForName = n
+			}
+		}
+
+		trackNumForClean := 0
+		if albumTrack.TrackNumExplicit {
+			trackNumForClean = albumTrack.TrackNum
+		}
+
+		// 1. Clean the title to handle cases where the track number is duplicated by Bandcamp
+		cleanedTitle := albumTrack.Title
+		if trackNumForClean > 0 {
+			cleanedTitle = cleanTrackTitle(albumTrack.Title, trackNumForClean)
+		}
+
+		// 2. Now work out artist and title for the template
+		albumArtistFromTitle := strings.TrimSpace(album.Artist)
+		if albumArtistFromTitle == "" {
+			albumTitleParts := strings.SplitN(album.Title, " - ", 2)
+			if len(albumTitleParts) > 1 {
+				albumArtistFromTitle = albumTitleParts[0]
+			}
+		}
+
+		fields := localTemplateFields(matchedLocalTrack)
+		fields["track"] = strconv.Itoa(trackNumForName)
+		fields["album"] = album.Title
+		fields["albumartist"] = albumArtistFromTitle
+		fields["label"] = album.Label
+		fields["catno"] = album.CatalogNumber
+		fields["position"] = albumTrack.Position
+		fields["isrc"] = albumTrack.ISRC
+		if album.DiscTotal > 1 {
+			fields["disc"] = strconv.Itoa(albumTrack.DiscNum)
+			fields["disctotal"] = strconv.Itoa(album.DiscTotal)
+		} else if album.DiscTotal == 1 {
+			fields["disc"], fields["disctotal"] = "", ""
+		}
+		if album.Year > 0 {
+			fields["year"] = strconv.Itoa(album.Year)
+		}
+		fields["bpm"] = ""
+		if parts := strings.SplitN(cleanedTitle, " - ", 2); len(parts) == 2 {
+			// Title is likely "Artist - Title", so split it.
+			fields["artist"] = strings.TrimSpace(parts[0])
+			fields["title"] = strings.TrimSpace(parts[1])
+		} else if strings.TrimSpace(albumTrack.Artist) != "" {
+			// Track contains artist info, so use it.
+			fields["artist"] = strings.TrimSpace(albumTrack.Artist)
+			fields["title"] = cleanedTitle
+		} else {
+			// Fall back to the album artist; may still be empty.
+			fields["artist"] = albumArtistFromTitle
+			fields["title"] = cleanedTitle
+		}
+		proposedName := tmpl.Render(fields, ext)
+
+		explain := explanations[i][j]
+		explain.RunnerUp, explain.RunnerUpScore = runnerUpTrack(scores, i, j, album)
+		result.Tracks = append(result.Tracks, MatchedTrack{
+			LocalPath:       matchedLocalTrack.Path,
+			OriginalName:    matchedLocalTrack.OriginalName,
+			ProposedNewName: proposedName,
+			Confidence:      scores[i][j],
+			Status:          fmt.Sprintf("%s Match", album.Source),
+			Explanation:     &explain,
+		})
 	}
-	return a.fetchBandcampAlbum(url)
-}
 
-func (a *App) fetchBandcampAlbum(url string) (*AlbumData, error) {
-	album, err := a.fetchBandcampData(url)
-	if err != nil {
-		return nil, err
-	}
-	tracks := make([]AlbumTrack, 0, len(album.TrackInfo))
-	for _, bcTrack := range album.TrackInfo {
-		artist := ""
-		if bcTrack.Artist != nil {
-			artist = *bcTrack.Artist
+	// Files left over are extras (bonus tracks, wrong release); keep them in
+	// the list with their name unchanged so they are not silently dropped.
+	for j, local := range localTracks {
+		if matchedLocal[j] {
+			continue
 		}
-		tracks = append(tracks, AlbumTrack{
-			Title:            bcTrack.Title,
-			Artist:           artist,
-			TrackNum:         bcTrack.TrackNum,
-			TrackNumExplicit: true,
-			TrackID:          0,
+		result.Tracks = append(result.Tracks, MatchedTrack{
+			LocalPath:       local.Path,
+			OriginalName:    local.OriginalName,
+			ProposedNewName: local.OriginalName,
+			Status:          statusUnmatched,
 		})
 	}
-	return &AlbumData{
-		Artist: album.Artist,
-		Title:  album.Current.Title,
-		Tracks: tracks,
-		Source: "Bandcamp",
-	}, nil
-}
-
-func (a *App) fetchBandcampData(url string) (*BandcampAlbum, error) {
-	res, err := getWithUserAgent(url)
-	if err != nil {
-		return nil, err
-	}
-	defer res.Body.Close()
-
-	if res.StatusCode != 200 {
-		return nil, fmt.Errorf("status code error: %d %s", res.StatusCode, res.Status)
-	}
-
-	doc, err := goquery.NewDocumentFromReader(res.Body)
-	if err != nil {
-		return nil, err
-	}
 
-	data := doc.Find("script[data-tralbum]").AttrOr("data-tralbum", "")
-	if data == "" {
-		return nil, fmt.Errorf("could not find album data on page")
-	}
-
-	var album BandcampAlbum
-	if err := json.Unmarshal([]byte(data), &album); err != nil {
-		return nil, fmt.Errorf("failed to unmarshal album data: %w", err)
-	}
-
-	return &album, nil
-}
-
-func (a *App) fetchBeatportData(url string) (*AlbumData, error) {
-	res, err := getWithUserAgent(url)
-	if err != nil {
-		return nil, err
-	}
-	defer res.Body.Close()
-
-	if res.StatusCode != 200 {
-		return nil, fmt.Errorf("status code error: %d %s", res.StatusCode, res.Status)
-	}
+	return result
+}
+
+// MatchExplanation breaks a match's confidence down into the signals it was
+// computed from. Scores are raw Sørensen–Dice similarities before the
+// track-number bonus.
+type MatchExplanation struct {
+	Signal           string  `json:"signal"`
+	TitleScore       float64 `json:"titleScore"`
+	FullScore        float64 `json:"fullScore"`
+	TagTitleScore    float64 `json:"tagTitleScore"`
+	TagFullScore     float64 `json:"tagFullScore"`
+	TrackNumberBonus bool    `json:"trackNumberBonus"`
+	Duration         string  `json:"duration"`
+	DurationDelta    float64 `json:"durationDelta"`
+	RunnerUp         string  `json:"runnerUp"`
+	RunnerUpScore    float64 `json:"runnerUpScore"`
+}
+
+// Signals reported in MatchExplanation.Signal.
+const (
+	signalNone          = "none"
+	signalFilenameTitle = "filename-title"
+	signalFilenameFull  = "filename-full"
+	signalTagTitle      = "tag-title"
+	signalTagFull       = "tag-full"
+)
 
-	doc, err := goquery.NewDocumentFromReader(res.Body)
-	if err != nil {
-		return nil, err
-	}
+// Duration agreement reported in MatchExplanation.Duration. It is empty when
+// either side has no duration.
+const (
+	durationAgree    = "agree"
+	durationDisagree = "disagree"
+)
 
-	album := AlbumData{Source: "Beatport"}
-	releaseID := extractBeatportReleaseID(url)
+// Durations within durationTolerance seconds count as the same recording and
+// add durationBonus. A gap larger than both durationMismatchSeconds and
+// durationMismatchRatio of the release length (an extended mix against the
+// radio edit, say) subtracts durationPenalty.
+const (
+	durationTolerance       = 3.0
+	durationBonus           = 0.1
+	durationMismatchSeconds = 15.0
+	durationMismatchRatio   = 0.05
+	durationPenalty         = 0.2
+)
 
-	if ogTitle := strings.TrimSpace(doc.Find("meta[property='og:title']").AttrOr("content", "")); ogTitle != "" {
-		title, artist := parseBeatportMetaTitle(ogTitle)
-		if album.Title == "" {
-			album.Title = title
-		}
-		if album.Artist == "" {
-			album.Artist = artist
-		}
-	}
+// minMatchScore is the least a pair must score to be matched at all, so a
+// file sharing nothing with any track (a bonus interview, say) is reported
+// as unmatched rather than given a track's name. A lone duration or track
+// number agreement is enough.
+const minMatchScore = 0.05
 
-	if tracks, title, artist := parseBeatportJSONLD(doc); len(tracks) > 0 {
-		if album.Title == "" && title != "" {
-			album.Title = title
-		}
-		if album.Artist == "" && artist != "" {
-			album.Artist = artist
+// scoreAlbum scores every album track (rows) against every local file
+// (columns).
+func scoreAlbum(album *AlbumData, localTracks []LocalTrack) ([][]float64, [][]MatchExplanation) {
+	sm := metrics.NewSorensenDice()
+	sm.CaseSensitive = false
+	scores := make([][]float64, len(album.Tracks))
+	explanations := make([][]MatchExplanation, len(album.Tracks))
+	for i, albumTrack := range album.Tracks {
+		log.Printf("Processing Album Track: %s (Num: %d)", albumTrack.Title, albumTrack.TrackNum)
+		scores[i] = make([]float64, len(localTracks))
+		explanations[i] = make([]MatchExplanation, len(localTracks))
+		for j, local := range localTracks {
+			scores[i][j], explanations[i][j] = scoreTrackPair(albumTrack, local, album, sm)
 		}
-		album.Tracks = tracks
-		return &album, nil
 	}
-
-	tracks, orderMap := parseBeatportNextData(doc, releaseID)
-	if len(tracks) > 0 {
-		album.Tracks = tracks
-		return &album, nil
-	}
-
-	if tracks := parseBeatportDataJSON(doc, releaseID, orderMap); len(tracks) > 0 {
-		album.Tracks = tracks
-		return &album, nil
-	}
-
-	return nil, fmt.Errorf("could not find Beatport track data on page")
+	return scores, explanations
 }
 
-func getWithUserAgent(url string) (*http.Response, error) {
-	req, err := http.NewRequest("GET", url, nil)
-	if err != nil {
-		return nil, err
-	}
-	req.Header.Set("User-Agent", "Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_7) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Safari/537.36")
-	return http.DefaultClient.Do(req)
-}
+// scoreTrackPair rates how well a local file matches an album track, from 0
+// to 1, using the filename, the tags, the track number and the duration.
+func scoreTrackPair(albumTrack AlbumTrack, local LocalTrack, album *AlbumData, sm *metrics.SorensenDice) (float64, MatchExplanation) {
+	explain := MatchExplanation{Signal: signalNone}
+	localNameToCompare := strings.TrimSuffix(local.OriginalName, filepath.Ext(local.OriginalName))
+	normalizedLocal := normalizeForMatch(localNameToCompare)
 
-func parseBeatportMetaTitle(s string) (string, string) {
-	s = strings.TrimSpace(strings.ReplaceAll(s, " on Beatport", ""))
-	if strings.Contains(s, " by ") {
-		parts := strings.SplitN(s, " by ", 2)
-		return strings.TrimSpace(parts[0]), strings.TrimSpace(parts[1])
+	// Calculate similarity based on filename
+	normalizedTitle := normalizeForMatch(albumTrack.Title)
+	if normalizedTitle != "" && hasTokenOverlap(normalizedTitle, normalizedLocal) {
+		explain.TitleScore = strutil.Similarity(normalizedTitle, normalizedLocal, sm)
 	}
-	return strings.TrimSpace(s), ""
-}
-
-func extractBeatportReleaseID(url string) int {
-	parts := strings.Split(strings.TrimRight(url, "/"), "/")
-	if len(parts) == 0 {
-		return 0
+	normalizedFull := ""
+	if strings.TrimSpace(albumTrack.Artist) != "" {
+		normalizedFull = normalizeForMatch(albumTrack.Artist + " - " + albumTrack.Title)
+	} else if album.Artist != "" {
+		normalizedFull = normalizeForMatch(album.Artist + " - " + albumTrack.Title)
 	}
-	last := parts[len(parts)-1]
-	if n, err := strconv.Atoi(last); err == nil {
-		return n
+	if normalizedFull != "" && hasTokenOverlap(normalizedFull, normalizedLocal) {
+		explain.FullScore = strutil.Similarity(normalizedFull, normalizedLocal, sm)
 	}
-	return 0
-}
 
-func parseBeatportJSONLD(doc *goquery.Document) ([]AlbumTrack, string, string) {
-	var bestTracks []AlbumTrack
-	var bestTitle string
-	var bestArtist string
-
-	doc.Find("script[type='application/ld+json']").Each(func(_ int, sel *goquery.Selection) {
-		raw := strings.TrimSpace(sel.Text())
-		if raw == "" {
-			return
-		}
-		var data interface{}
-		if err := json.Unmarshal([]byte(raw), &data); err != nil {
-			return
+	// Calculate similarity based on tags (if available)
+	if local.TagTitle != "" {
+		tagBase := normalizeForMatch(local.TagTitle)
+		if tagBase != "" && normalizedTitle != "" && hasTokenOverlap(normalizedTitle, tagBase) {
+			explain.TagTitleScore = strutil.Similarity(normalizedTitle, tagBase, sm)
 		}
-		tracks, title, artist := parseLDData(data)
-		if len(tracks) > len(bestTracks) {
-			bestTracks = tracks
-			bestTitle = title
-			bestArtist = artist
-		}
-	})
-
-	return bestTracks, bestTitle, bestArtist
-}
-
-func parseLDData(data interface{}) ([]AlbumTrack, string, string) {
-	switch v := data.(type) {
-	case []interface{}:
-		var bestTracks []AlbumTrack
-		var bestTitle string
-		var bestArtist string
-		for _, item := range v {
-			tracks, title, artist := parseLDData(item)
-			if len(tracks) > len(bestTracks) {
-				bestTracks = tracks
-				bestTitle = title
-				bestArtist = artist
+		if local.TagArtist != "" && normalizedFull != "" {
+			tagFull := normalizeForMatch(local.TagArtist + " - " + local.TagTitle)
+			if tagFull != "" && hasTokenOverlap(normalizedFull, tagFull) {
+				explain.TagFullScore = strutil.Similarity(normalizedFull, tagFull, sm)
 			}
 		}
-		return bestTracks, bestTitle, bestArtist
-	case map[string]interface{}:
-		if graph, ok := v["@graph"]; ok {
-			return parseLDData(graph)
-		}
-		typ := getStringFromMap(v, "@type")
-		if typ == "MusicAlbum" || typ == "MusicRelease" || typ == "MusicPlaylist" {
-			title := getStringFromMap(v, "name", "title")
-			artist := parseArtistsField(v["byArtist"])
-			if artist == "" {
-				artist = parseArtistsField(v["artist"])
-			}
-			tracks := parseLDTracks(v["track"])
-			return tracks, title, artist
-		}
 	}
-	return nil, "", ""
-}
 
-func parseLDTracks(value interface{}) []AlbumTrack {
-	arr, ok := value.([]interface{})
-	if !ok || len(arr) == 0 {
-		return nil
-	}
-	tracks := make([]AlbumTrack, 0, len(arr))
-	for i, item := range arr {
-		m, ok := item.(map[string]interface{})
-		if !ok {
-			continue
-		}
-		title := getStringFromMap(m, "name", "title")
-		artist := parseArtistsField(m["byArtist"])
-		if artist == "" {
-			artist = parseArtistsField(m["artist"])
-		}
-		trackNum, explicit := extractTrackNumberFromObj(m)
-		if trackNum == 0 {
-			trackNum = i + 1
-		}
-		if title != "" {
-			tracks = append(tracks, AlbumTrack{
-				Title:            title,
-				Artist:           artist,
-				TrackNum:         trackNum,
-				TrackNumExplicit: explicit,
-			})
+	// The strongest signal wins; filename signals win ties.
+	rating := 0.0
+	for _, signal := range []struct {
+		name  string
+		score float64
+	}{
+		{signalFilenameTitle, explain.TitleScore},
+		{signalFilenameFull, explain.FullScore},
+		{signalTagTitle, explain.TagTitleScore},
+		{signalTagFull, explain.TagFullScore},
+	} {
+		if signal.score > rating {
+			rating = signal.score
+			explain.Signal = signal.name
 		}
 	}
-	return tracks
-}
-
-func parseBeatportDataJSON(doc *goquery.Document, releaseID int, orderMap map[int]int) []AlbumTrack {
-	var tracks []AlbumTrack
-	doc.Find("span[data-json]").Each(func(_ int, sel *goquery.Selection) {
-		data := strings.TrimSpace(sel.AttrOr("data-json", ""))
-		if data == "" {
-			return
-		}
-		var obj map[string]interface{}
-		if err := json.Unmarshal([]byte(data), &obj); err != nil {
-			return
-		}
-		if releaseID != 0 {
-			if objRelease := extractReleaseIDFromObj(obj); objRelease != 0 && objRelease != releaseID {
-				return
-			}
-		}
-		trackID := extractTrackIDFromObj(obj)
-		title := getStringFromMap(obj, "title", "name")
-		artist := parseArtistsField(obj["artists"])
-		mixName := getStringFromMap(obj, "mixName", "mix_name")
-		if mixName != "" && !strings.Contains(strings.ToLower(title), strings.ToLower(mixName)) {
-			title = strings.TrimSpace(title) + " (" + mixName + ")"
-		}
-		trackNum, explicit := extractTrackNumberFromObj(obj)
-		if trackNum == 0 && orderMap != nil && trackID != 0 {
-			if n, ok := orderMap[trackID]; ok {
-				trackNum = n
-				explicit = true
-			}
-		}
-		if trackNum == 0 {
-			trackNum = len(tracks) + 1
-		}
-		if title != "" {
-			tracks = append(tracks, AlbumTrack{
-				Title:            title,
-				Artist:           artist,
-				TrackNum:         trackNum,
-				TrackNumExplicit: explicit,
-				TrackID:          trackID,
-			})
-		}
-	})
-	return tracks
-}
 
-func parseBeatportNextData(doc *goquery.Document, releaseID int) ([]AlbumTrack, map[int]int) {
-	raw := strings.TrimSpace(doc.Find("script#__NEXT_DATA__").Text())
-	if raw == "" {
-		return nil, nil
-	}
-	var data interface{}
-	if err := json.Unmarshal([]byte(raw), &data); err != nil {
-		return nil, nil
-	}
-	orderMap := findBeatportReleaseTrackOrder(data, releaseID)
-	if tracks := extractBeatportTracksFromDehydrated(data, releaseID, orderMap); len(tracks) > 0 {
-		return tracks, orderMap
-	}
-	return findTrackListInJSON(data, releaseID, orderMap), orderMap
-}
+	log.Printf("  Comparing Album '%s' with Local '%s' (Tags: '%s' - '%s')", albumTrack.Title, localNameToCompare, local.TagArtist, local.TagTitle)
+	log.Printf("  Similarity Rating: %f (%s)", rating, explain.Signal)
 
-func findTrackListInJSON(data interface{}, releaseID int, orderMap map[int]int) []AlbumTrack {
-	var candidates [][]map[string]interface{}
-	collectTrackArrays(data, &candidates)
-	if len(candidates) == 0 {
-		return nil
-	}
-	best := scoreTrackCandidate(candidates[0], releaseID, orderMap)
-	for _, c := range candidates[1:] {
-		cand := scoreTrackCandidate(c, releaseID, orderMap)
-		if cand.Score > best.Score {
-			best = cand
-		}
-	}
-	ordered := orderTrackObjects(best.Tracks, orderMap)
-	tracks := make([]AlbumTrack, 0, len(ordered))
-	for i, obj := range ordered {
-		trackID := extractTrackIDFromObj(obj)
-		title := getStringFromMap(obj, "name", "title")
-		artist := parseArtistsField(obj["artists"])
-		if artist == "" {
-			artist = parseArtistsField(obj["artist"])
-		}
-		mixName := getStringFromMap(obj, "mixName", "mix_name")
-		if mixName != "" && !strings.Contains(strings.ToLower(title), strings.ToLower(mixName)) {
-			title = strings.TrimSpace(title) + " (" + mixName + ")"
-		}
-		trackNum, explicit := extractTrackNumberFromObj(obj)
-		if trackNum == 0 && orderMap != nil {
-			if trackID != 0 {
-				if n, ok := orderMap[trackID]; ok {
-					trackNum = n
-					explicit = true
+	if albumTrack.TrackNumExplicit && albumTrack.TrackNum > 0 {
+		if n := localTrackNumber(local, album); n > 0 && n == albumTrack.TrackNum && discAgrees(local, album, albumTrack) {
+			if rating < 0.9 {
+				explain.TrackNumberBonus = true
+				rating += 0.12
+				if rating > 1.0 {
+					rating = 1.0
 				}
 			}
 		}
-		if trackNum == 0 {
-			trackNum = i + 1
-		}
-		if title != "" {
-			tracks = append(tracks, AlbumTrack{
-				Title:            title,
-				Artist:           artist,
-				TrackNum:         trackNum,
-				TrackNumExplicit: explicit,
-				TrackID:          trackID,
-			})
-		}
 	}
-	return tracks
-}
 
-func extractBeatportTracksFromDehydrated(data interface{}, releaseID int, orderMap map[int]int) []AlbumTrack {
-	root, ok := data.(map[string]interface{})
-	if !ok {
-		return nil
-	}
-	props, _ := root["props"].(map[string]interface{})
-	pageProps, _ := props["pageProps"].(map[string]interface{})
-	dehydrated, _ := pageProps["dehydratedState"].(map[string]interface{})
-	queries, _ := dehydrated["queries"].([]interface{})
-	if len(queries) == 0 {
-		return nil
-	}
-	for _, q := range queries {
-		qm, ok := q.(map[string]interface{})
-		if !ok {
-			continue
-		}
-		qk, ok := qm["queryKey"].([]interface{})
-		if !ok || len(qk) < 2 {
-			continue
-		}
-		if key, ok := qk[0].(string); !ok || key != "tracks" {
-			continue
-		}
-		params, ok := qk[1].(map[string]interface{})
-		if !ok {
-			continue
-		}
-		if releaseID != 0 {
-			if rid := parseIntFromAny(params["release_id"], params["releaseId"]); rid != releaseID {
-				continue
-			}
-		}
-		state, _ := qm["state"].(map[string]interface{})
-		dataNode, _ := state["data"].(map[string]interface{})
-		results, _ := dataNode["results"].([]interface{})
-		if len(results) == 0 {
-			continue
-		}
-		return buildBeatportTracksFromOrderedResults(results, orderMap)
-	}
-	return nil
-}
-
-func buildBeatportTracksFromOrderedResults(results []interface{}, orderMap map[int]int) []AlbumTrack {
-	objs := make([]map[string]interface{}, 0, len(results))
-	for _, item := range results {
-		if m, ok := item.(map[string]interface{}); ok {
-			objs = append(objs, m)
-		}
-	}
-	if len(objs) == 0 {
-		return nil
-	}
-	tracks := make([]AlbumTrack, 0, len(objs))
-	for i, obj := range objs {
-		trackID := extractTrackIDFromObj(obj)
-		title := getStringFromMap(obj, "name", "title")
-		artist := parseArtistsField(obj["artists"])
-		if artist == "" {
-			artist = parseArtistsField(obj["artist"])
-		}
-		mixName := getStringFromMap(obj, "mixName", "mix_name")
-		if mixName != "" && !strings.Contains(strings.ToLower(title), strings.ToLower(mixName)) {
-			title = strings.TrimSpace(title) + " (" + mixName + ")"
-		}
-		trackNum, explicit := extractTrackNumberFromObj(obj)
-		if trackNum == 0 && trackID != 0 {
-			if n, ok := orderMap[trackID]; ok {
-				trackNum = n
-				explicit = true
-			}
-		}
-		if trackNum == 0 {
-			trackNum = i + 1
-		}
-		if title != "" {
-			tracks = append(tracks, AlbumTrack{
-				Title:            title,
-				Artist:           artist,
-				TrackNum:         trackNum,
-				TrackNumExplicit: explicit,
-				TrackID:          trackID,
-			})
+	if albumTrack.Duration > 0 && local.Duration > 0 {
+		explain.DurationDelta = local.Duration - albumTrack.Duration
+		delta := math.Abs(explain.DurationDelta)
+		switch {
+		case delta <= durationTolerance:
+			explain.Duration = durationAgree
+			rating = math.Min(rating+durationBonus, 1.0)
+		case delta > durationMismatchSeconds && delta > albumTrack.Duration*durationMismatchRatio:
+			explain.Duration = durationDisagree
+			rating = math.Max(rating-durationPenalty, 0)
 		}
 	}
-	return tracks
-}
-
-type trackListCandidate struct {
-	Tracks []map[string]interface{}
-	Score  int
+	return rating, explain
 }
 
-func scoreTrackCandidate(arr []map[string]interface{}, releaseID int, orderMap map[int]int) trackListCandidate {
-	score := len(arr)
-	nums := make([]int, 0, len(arr))
-	artistCount := 0
-	releaseMatch := 0
-	releaseSeen := 0
-	orderMatches := 0
-	for _, obj := range arr {
-		if num, _ := extractTrackNumberFromObj(obj); num > 0 {
-			nums = append(nums, num)
-		}
-		if _, ok := obj["artists"]; ok {
-			artistCount++
-		} else if _, ok := obj["artist"]; ok {
-			artistCount++
-		}
-		if releaseID != 0 {
-			if objRelease := extractReleaseIDFromObj(obj); objRelease != 0 {
-				releaseSeen++
-				if objRelease == releaseID {
-					releaseMatch++
-				}
-			}
-		}
-		if orderMap != nil {
-			if id := extractTrackIDFromObj(obj); id != 0 {
-				if _, ok := orderMap[id]; ok {
-					orderMatches++
-				}
-			}
+// runnerUpTrack finds the album track, other than the chosen one, that the
+// local file in column j scored best against.
+func runnerUpTrack(scores [][]float64, chosen int, j int, album *AlbumData) (string, float64) {
+	best := -1
+	for i := range scores {
+		if i != chosen && (best < 0 || scores[i][j] > scores[best][j]) {
+			best = i
 		}
 	}
-	score += artistCount
-	if len(nums) > 0 {
-		score += len(nums) * 3
-		if isSequentialFromOne(nums, len(arr)) {
-			score += 200
-		} else if isSequentialFromOne(nums, len(nums)) {
-			score += 100
-		}
-	}
-	if releaseID != 0 && releaseSeen > 0 {
-		if releaseMatch == releaseSeen && releaseMatch >= len(arr)/2 {
-			score += 1000
-		} else if releaseMatch > 0 {
-			score += releaseMatch * 10
-		}
+	if best < 0 || scores[best][j] <= 0 {
+		return "", 0
 	}
-	if orderMatches > 0 {
-		score += orderMatches * 5
-		if orderMatches >= len(arr)/2 {
-			score += 50
-		}
-	}
-	return trackListCandidate{Tracks: arr, Score: score}
+	return albumTrackLabel(album.Tracks[best], album.DiscTotal > 1), scores[best][j]
 }
 
-func isSequentialFromOne(nums []int, expected int) bool {
-	if len(nums) == 0 {
-		return false
-	}
-	seen := make(map[int]struct{}, len(nums))
-	for _, n := range nums {
-		if n <= 0 {
-			return false
-		}
-		seen[n] = struct{}{}
-	}
-	if len(seen) != len(nums) {
-		return false
+// albumTrackLabel shows a release track as "03. Title", "2-03. Title" on a
+// multi-disc release, or "A3. Title" when the store uses side positions.
+func albumTrackLabel(t AlbumTrack, multiDisc bool) string {
+	switch {
+	case t.Position != "":
+		return t.Position + ". " + t.Title
+	case multiDisc && t.DiscNum > 0 && t.TrackNum > 0:
+		return fmt.Sprintf("%d-%02d. %s", t.DiscNum, t.TrackNum, t.Title)
+	case t.TrackNum > 0:
+		return fmt.Sprintf("%02d. %s", t.TrackNum, t.Title)
 	}
-	if expected <= 0 {
-		expected = len(nums)
-	}
-	for i := 1; i <= expected; i++ {
-		if _, ok := seen[i]; !ok {
-			return false
-		}
-	}
-	return true
+	return t.Title
 }
 
-func orderTrackObjects(arr []map[string]interface{}, orderMap map[int]int) []map[string]interface{} {
-	if len(arr) == 0 {
-		return arr
-	}
-	if orderMap != nil && len(orderMap) > 0 {
-		ordered := make([]map[string]interface{}, 0, len(arr))
-		used := make([]bool, len(arr))
-		for i := 1; i <= len(orderMap); i++ {
-			for idx, obj := range arr {
-				if used[idx] {
-					continue
-				}
-				if id := extractTrackIDFromObj(obj); id != 0 {
-					if n, ok := orderMap[id]; ok && n == i {
-						ordered = append(ordered, obj)
-						used[idx] = true
-						break
-					}
-				}
-			}
-		}
-		if len(ordered) >= len(arr)/2 {
-			for idx, obj := range arr {
-				if !used[idx] {
-					ordered = append(ordered, obj)
-				}
-			}
-			return ordered
-		}
+// localTrackNumber returns the track number from the filename prefix, or from
+// the tags when the filename has none. Tagged numbers are only trusted when
+// the tagged album is unknown or agrees with the release being matched.
+func localTrackNumber(local LocalTrack, album *AlbumData) int {
+	base := strings.TrimSuffix(local.OriginalName, filepath.Ext(local.OriginalName))
+	if m := reDiscTrack.FindStringSubmatch(base); m != nil {
+		n, _ := strconv.Atoi(m[2])
+		return n
 	}
-	nums := make([]int, 0, len(arr))
-	countWithNum := 0
-	for _, obj := range arr {
-		if n, _ := extractTrackNumberFromObj(obj); n > 0 {
-			nums = append(nums, n)
-			countWithNum++
+	if num := extractTrackNumber(base); num != "" {
+		if n, err := strconv.Atoi(num); err == nil {
+			return n
 		}
 	}
-	if countWithNum == 0 {
-		return arr
-	}
-	if !isSequentialFromOne(nums, len(arr)) && countWithNum < len(arr)/2 {
-		return arr
-	}
-	ordered := make([]map[string]interface{}, 0, len(arr))
-	used := make([]bool, len(arr))
-	for i := 1; i <= len(arr); i++ {
-		found := false
-		for idx, obj := range arr {
-			if used[idx] {
-				continue
-			}
-			if n, _ := extractTrackNumberFromObj(obj); n == i {
-				ordered = append(ordered, obj)
-				used[idx] = true
-				found = true
-				break
-			}
-		}
-		if !found {
-			break
-		}
-	}
-	if len(ordered) == len(arr) {
-		return ordered
-	}
-	return arr
-}
-
-func collectTrackArrays(value interface{}, out *[][]map[string]interface{}) {
-	switch v := value.(type) {
-	case map[string]interface{}:
-		for _, nested := range v {
-			collectTrackArrays(nested, out)
-		}
-	case []interface{}:
-		if len(v) > 0 {
-			allTracks := true
-			arr := make([]map[string]interface{}, 0, len(v))
-			for _, item := range v {
-				obj, ok := item.(map[string]interface{})
-				if !ok || !isTrackLike(obj) {
-					allTracks = false
-					break
-				}
-				arr = append(arr, obj)
-			}
-			if allTracks {
-				*out = append(*out, arr)
-			}
-		}
-		for _, nested := range v {
-			collectTrackArrays(nested, out)
-		}
-	}
-}
-
-func isTrackLike(obj map[string]interface{}) bool {
-	title := getStringFromMap(obj, "name", "title")
-	if title == "" {
-		return false
-	}
-	if _, ok := obj["artists"]; ok {
-		return true
-	}
-	if _, ok := obj["artist"]; ok {
-		return true
-	}
-	return false
-}
-
-func extractReleaseIDFromObj(obj map[string]interface{}) int {
-	if v, ok := obj["releaseId"]; ok {
-		return parseIntFromAny(v)
-	}
-	if v, ok := obj["release_id"]; ok {
-		return parseIntFromAny(v)
-	}
-	if v, ok := obj["release"]; ok {
-		if m, ok := v.(map[string]interface{}); ok {
-			return parseIntFromAny(m["id"], m["releaseId"], m["release_id"])
-		}
+	if local.TagTrack > 0 && tagAlbumAgrees(local, album) {
+		return local.TagTrack
 	}
 	return 0
 }
 
-func extractTrackIDFromObj(obj map[string]interface{}) int {
-	return parseIntFromAny(obj["id"], obj["trackId"], obj["track_id"])
-}
-
-func parseBeatportTrackIDFromURL(s string) int {
-	if s == "" {
-		return 0
-	}
-	trimmed := strings.TrimRight(s, "/")
-	if trimmed == "" {
-		return 0
-	}
-	parts := strings.Split(trimmed, "/")
-	last := parts[len(parts)-1]
-	if n, err := strconv.Atoi(last); err == nil {
+// localDiscNumber returns the disc from a "1-03" filename prefix, or from the
+// tags when the filename has none.
+func localDiscNumber(local LocalTrack, album *AlbumData) int {
+	base := strings.TrimSuffix(local.OriginalName, filepath.Ext(local.OriginalName))
+	if m := reDiscTrack.FindStringSubmatch(base); m != nil {
+		n, _ := strconv.Atoi(m[1])
 		return n
 	}
-	return 0
-}
-
-func extractTrackIDFromAny(value interface{}) int {
-	switch v := value.(type) {
-	case string:
-		return parseBeatportTrackIDFromURL(v)
-	case map[string]interface{}:
-		if id := extractTrackIDFromObj(v); id != 0 {
-			return id
-		}
-		if url, ok := v["url"].(string); ok {
-			return parseBeatportTrackIDFromURL(url)
-		}
-	case int:
-		return v
-	case int64:
-		return int(v)
-	case float64:
-		if v > 0 {
-			return int(v)
-		}
-	case json.Number:
-		if n, err := v.Int64(); err == nil {
-			return int(n)
-		}
+	if local.TagDisc > 0 && tagAlbumAgrees(local, album) {
+		return local.TagDisc
 	}
 	return 0
 }
 
-func buildOrderMapFromTrackList(value interface{}) map[int]int {
-	arr, ok := value.([]interface{})
-	if !ok || len(arr) == 0 {
-		return nil
-	}
-	order := make(map[int]int, len(arr))
-	pos := 1
-	for _, item := range arr {
-		if id := extractTrackIDFromAny(item); id != 0 {
-			if _, exists := order[id]; !exists {
-				order[id] = pos
-				pos++
-			}
-		}
-	}
-	if len(order) == 0 {
-		return nil
-	}
-	return order
-}
-
-func findBeatportReleaseTrackOrder(value interface{}, releaseID int) map[int]int {
-	switch v := value.(type) {
-	case map[string]interface{}:
-		if releaseID != 0 {
-			if id := parseIntFromAny(v["id"]); id == releaseID {
-				if tracksVal, ok := v["tracks"]; ok {
-					if order := buildOrderMapFromTrackList(tracksVal); len(order) > 0 {
-						return order
-					}
-				}
-			}
-		}
-		for _, nested := range v {
-			if order := findBeatportReleaseTrackOrder(nested, releaseID); len(order) > 0 {
-				return order
-			}
-		}
-	case []interface{}:
-		for _, item := range v {
-			if order := findBeatportReleaseTrackOrder(item, releaseID); len(order) > 0 {
-				return order
-			}
-		}
-	}
-	return nil
-}
-
-func getStringFromMap(obj map[string]interface{}, keys ...string) string {
-	for _, key := range keys {
-		if val, ok := obj[key]; ok {
-			if s, ok := val.(string); ok {
-				return strings.TrimSpace(s)
-			}
-		}
-	}
-	return ""
-}
-
-func parseArtistsField(value interface{}) string {
-	switch v := value.(type) {
-	case string:
-		return strings.TrimSpace(v)
-	case map[string]interface{}:
-		return getStringFromMap(v, "name")
-	case []interface{}:
-		names := make([]string, 0, len(v))
-		for _, item := range v {
-			switch t := item.(type) {
-			case string:
-				if strings.TrimSpace(t) != "" {
-					names = append(names, strings.TrimSpace(t))
-				}
-			case map[string]interface{}:
-				if name := getStringFromMap(t, "name"); name != "" {
-					names = append(names, name)
-				}
-			}
-		}
-		return strings.Join(names, ", ")
+// discAgrees reports whether a local file can be on the album track's disc.
+// Unknown discs on either side never disagree.
+func discAgrees(local LocalTrack, album *AlbumData, albumTrack AlbumTrack) bool {
+	if albumTrack.DiscNum == 0 || album.DiscTotal < 2 {
+		return true
 	}
-	return ""
+	disc := localDiscNumber(local, album)
+	return disc == 0 || disc == albumTrack.DiscNum
 }
 
-func extractTrackNumberFromObj(obj map[string]interface{}) (int, bool) {
-	keys := []string{"trackNumber", "track_number", "position", "number", "index", "trackNo"}
-	explicit := false
-	values := make([]interface{}, 0, len(keys))
-	for _, key := range keys {
-		if val, ok := obj[key]; ok {
-			explicit = true
-			values = append(values, val)
-		}
+func tagAlbumAgrees(local LocalTrack, album *AlbumData) bool {
+	tagAlbum := normalizeForMatch(local.TagAlbum)
+	if tagAlbum == "" || album == nil {
+		return true
 	}
-	if !explicit {
-		return 0, false
+	releaseTitle := normalizeForMatch(album.Title)
+	if releaseTitle == "" {
+		return true
 	}
-	return parseIntFromAny(values...), true
-}
-
-func parseIntFromAny(values ...interface{}) int {
-	for _, value := range values {
-		switch v := value.(type) {
-		case int:
-			return v
-		case int64:
-			return int(v)
-		case float64:
-			if v > 0 {
-				return int(v)
-			}
-		case json.Number:
-			if n, err := v.Int64(); err == nil {
-				return int(n)
-			}
-		case string:
-			if n, err := strconv.Atoi(strings.TrimSpace(v)); err == nil {
-				return n
-			}
-		}
+	if strings.Contains(releaseTitle, tagAlbum) || strings.Contains(tagAlbum, releaseTitle) {
+		return true
 	}
-	return 0
+	sm := metrics.NewSorensenDice()
+	sm.CaseSensitive = false
+	return strutil.Similarity(tagAlbum, releaseTitle, sm) >= 0.6
 }
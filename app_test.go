@@ -0,0 +1,108 @@
+package main
+
+import (
+	"math"
+	"testing"
+)
+
+func TestMatchAlbumLeavesUnrelatedFilesUnmatched(t *testing.T) {
+	album := &AlbumData{
+		Artist: "A",
+		Title:  "Weather",
+		Source: "Bandcamp",
+		Tracks: []AlbumTrack{
+			{Title: "Sunrise", TrackNum: 1, TrackNumExplicit: true},
+			{Title: "Moonlight", TrackNum: 2, TrackNumExplicit: true},
+			{Title: "Thunderstorm", TrackNum: 3, TrackNumExplicit: true},
+		},
+	}
+	local := []LocalTrack{
+		{Path: "/music/01 A - Sunrise.flac", OriginalName: "01 A - Sunrise.flac"},
+		{Path: "/music/02 A - Moonlight.flac", OriginalName: "02 A - Moonlight.flac"},
+		{Path: "/music/bonus interview.flac", OriginalName: "bonus interview.flac"},
+	}
+	tmpl, err := resolveRenameTemplate("")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	result := matchAlbum(album, "https://a.bandcamp.com/album/weather", album.Source, local, tmpl)
+	if len(result.Missing) != 1 || result.Missing[0].Title != "Thunderstorm" {
+		t.Fatalf("missing = %+v, want only Thunderstorm", result.Missing)
+	}
+	for _, track := range result.Tracks {
+		if track.OriginalName != "bonus interview.flac" {
+			continue
+		}
+		if track.Status != statusUnmatched || track.ProposedNewName != track.OriginalName {
+			t.Errorf("bonus file = %+v, want it unmatched and unchanged", track)
+		}
+		return
+	}
+	t.Fatal("bonus file missing from the result")
+}
+
+func TestLocalTrackNumberFallsBackToTags(t *testing.T) {
+	album := &AlbumData{Title: "Night Drive"}
+	for _, tc := range []struct {
+		local LocalTrack
+		want  int
+	}{
+		{LocalTrack{OriginalName: "05 Tunnel Lights.flac", TagTrack: 3}, 5},
+		{LocalTrack{OriginalName: "Tunnel Lights.flac", TagTrack: 3}, 3},
+		{LocalTrack{OriginalName: "Tunnel Lights.flac", TagTrack: 3, TagAlbum: "Night Drive (Deluxe)"}, 3},
+		// The tags belong to another release.
+		{LocalTrack{OriginalName: "Tunnel Lights.flac", TagTrack: 3, TagAlbum: "Greatest Hits"}, 0},
+	} {
+		if got := localTrackNumber(tc.local, album); got != tc.want {
+			t.Errorf("localTrackNumber(%q, album %q) = %d, want %d", tc.local.OriginalName, tc.local.TagAlbum, got, tc.want)
+		}
+	}
+}
+
+func TestMatchAlbumExplainsMatches(t *testing.T) {
+	album := &AlbumData{
+		Artist: "Kora",
+		Title:  "Night Drive",
+		Source: "Bandcamp",
+		Tracks: []AlbumTrack{
+			{Title: "Night Drive", TrackNum: 1, TrackNumExplicit: true},
+			{Title: "Night Drive Reprise", TrackNum: 2, TrackNumExplicit: true},
+			{Title: "Tunnel Lights", TrackNum: 3, TrackNumExplicit: true},
+		},
+	}
+	local := []LocalTrack{
+		{Path: "/music/01 Night Drive.flac", OriginalName: "01 Night Drive.flac"},
+		{Path: "/music/02 Night Drive (Reprise).flac", OriginalName: "02 Night Drive (Reprise).flac"},
+		{Path: "/music/untitled.flac", OriginalName: "untitled.flac", TagArtist: "Kora", TagTitle: "Tunnel Lite", TagTrack: 3},
+	}
+	tmpl, err := resolveRenameTemplate("")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	result := matchAlbum(album, "https://kora.bandcamp.com/album/night-drive", album.Source, local, tmpl)
+	explained := map[string]MatchedTrack{}
+	for _, track := range result.Tracks {
+		if track.Explanation == nil {
+			t.Fatalf("%s has no explanation", track.OriginalName)
+		}
+		explained[track.OriginalName] = track
+	}
+
+	exact := explained["01 Night Drive.flac"]
+	if e := exact.Explanation; e.Signal != signalFilenameTitle || e.TitleScore != 1 || e.TrackNumberBonus {
+		t.Errorf("exact filename: %+v, want a filename-title match without the track-number bonus", *e)
+	}
+	if e := exact.Explanation; e.RunnerUp != "02. Night Drive Reprise" || e.RunnerUpScore <= 0 || e.RunnerUpScore >= exact.Confidence {
+		t.Errorf("exact filename runner-up: %q %v", e.RunnerUp, e.RunnerUpScore)
+	}
+
+	tagged := explained["untitled.flac"]
+	if e := tagged.Explanation; e.Signal != signalTagFull || !e.TrackNumberBonus || e.TitleScore != 0 || e.FullScore != 0 {
+		t.Errorf("tagged file: %+v, want a tag-full match with the track-number bonus", *e)
+	}
+	if want := tagged.Explanation.TagFullScore + 0.12; math.Abs(tagged.Confidence-want) > 1e-9 {
+		t.Errorf("tagged file confidence = %v, want %v", tagged.Confidence, want)
+	}
+}
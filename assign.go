@@ -0,0 +1,119 @@
+package main
+
+import "math"
+
+// solveAssignment pairs rows (album tracks) with columns (local files) so the
+// total score is as high as possible, using the Hungarian algorithm. Scores
+// are expected in [0, 1]. It returns the column chosen for each row, or -1
+// when the row is left unmatched: there were fewer columns than rows, or the
+// best pairing for it scored below minScore. A pair scoring 0 has nothing in
+// common and is never kept, whatever minScore is.
+func solveAssignment(scores [][]float64, minScore float64) []int {
+	rows := len(scores)
+	assigned := make([]int, rows)
+	for i := range assigned {
+		assigned[i] = -1
+	}
+	if rows == 0 || len(scores[0]) == 0 {
+		return assigned
+	}
+	cols := len(scores[0])
+
+	// Pairs below the threshold are worth nothing, so the solver is free to
+	// give their column to a row that can use it.
+	weight := func(i, j int) float64 {
+		if scores[i][j] < minScore {
+			return 0
+		}
+		return scores[i][j]
+	}
+
+	// The solver needs at least as many columns as rows; transpose if not.
+	if rows <= cols {
+		for i, j := range hungarian(rows, cols, func(i, j int) float64 { return 1 - weight(i, j) }) {
+			assigned[i] = j
+		}
+	} else {
+		for j, i := range hungarian(cols, rows, func(j, i int) float64 { return 1 - weight(i, j) }) {
+			if i >= 0 {
+				assigned[i] = j
+			}
+		}
+	}
+
+	for i, j := range assigned {
+		if j >= 0 && (scores[i][j] <= 0 || scores[i][j] < minScore) {
+			assigned[i] = -1
+		}
+	}
+	return assigned
+}
+
+// hungarian finds the minimum-cost assignment of n rows to m columns (n <= m)
+// and returns the column for each row. This is the O(n²m) potentials version
+// of the algorithm.
+func hungarian(n, m int, cost func(i, j int) float64) []int {
+	u := make([]float64, n+1)
+	v := make([]float64, m+1)
+	owner := make([]int, m+1) // row (1-based) assigned to each column, 0 if free
+	way := make([]int, m+1)
+	minv := make([]float64, m+1)
+	used := make([]bool, m+1)
+
+	for i := 1; i <= n; i++ {
+		owner[0] = i
+		j0 := 0
+		for j := range minv {
+			minv[j] = math.Inf(1)
+			used[j] = false
+		}
+		for {
+			used[j0] = true
+			i0 := owner[j0]
+			delta := math.Inf(1)
+			j1 := 0
+			for j := 1; j <= m; j++ {
+				if used[j] {
+					continue
+				}
+				cur := cost(i0-1, j-1) - u[i0] - v[j]
+				if cur < minv[j] {
+					minv[j] = cur
+					way[j] = j0
+				}
+				if minv[j] < delta {
+					delta = minv[j]
+					j1 = j
+				}
+			}
+			for j := 0; j <= m; j++ {
+				if used[j] {
+					u[owner[j]] += delta
+					v[j] -= delta
+				} else {
+					minv[j] -= delta
+				}
+			}
+			j0 = j1
+			if owner[j0] == 0 {
+				break
+			}
+		}
+		for j0 != 0 {
+			j1 := way[j0]
+			owner[j0] = owner[j1]
+			j0 = j1
+		}
+	}
+
+	result := make([]int, n)
+	for i := range result {
+		result[i] = -1
+	}
+	for j := 1; j <= m; j++ {
+		if owner[j] != 0 {
+			result[owner[j]-1] = j - 1
+		}
+	}
+	return result
+}
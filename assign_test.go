@@ -0,0 +1,65 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSolveAssignment(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		scores   [][]float64
+		minScore float64
+		want     []int
+	}{
+		{
+			name:   "empty",
+			scores: nil,
+			want:   []int{},
+		},
+		{
+			// Greedy would give row 0 column 0 and leave row 1 with 0.1.
+			name:   "best total, not best first",
+			scores: [][]float64{{0.9, 0.8}, {0.85, 0.1}},
+			want:   []int{1, 0},
+		},
+		{
+			name:   "more columns than rows",
+			scores: [][]float64{{0.1, 0.2, 0.9}, {0.7, 0.1, 0.2}},
+			want:   []int{2, 0},
+		},
+		{
+			name:   "more rows than columns",
+			scores: [][]float64{{0.3}, {0.9}, {0.5}},
+			want:   []int{-1, 0, -1},
+		},
+		{
+			name:     "below threshold",
+			scores:   [][]float64{{0.9, 0.2}, {0.3, 0.2}},
+			minScore: 0.25,
+			want:     []int{0, -1},
+		},
+		{
+			// The leftover row only has a zero-score column left.
+			name:   "zero scores are never kept",
+			scores: [][]float64{{0.9, 0}, {0.8, 0}},
+			want:   []int{0, -1},
+		},
+	} {
+		if got := solveAssignment(tc.scores, tc.minScore); !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("%s: got %v, want %v", tc.name, got, tc.want)
+		}
+	}
+}
+
+func TestHungarian(t *testing.T) {
+	costs := [][]float64{
+		{4, 1, 3},
+		{2, 0, 5},
+		{3, 2, 2},
+	}
+	got := hungarian(3, 3, func(i, j int) float64 { return costs[i][j] })
+	if want := []int{1, 0, 2}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
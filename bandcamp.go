@@ -0,0 +1,252 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	neturl "net/url"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+type BandcampAlbum struct {
+	Artist    string          `json:"artist"`
+	TrackInfo []BandcampTrack `json:"trackinfo"`
+	Current   CurrentInfo     `json:"current"`
+	// ItemType is "album" or "track". A track that belongs to an album links
+	// to it in AlbumURL.
+	ItemType string  `json:"item_type"`
+	AlbumURL *string `json:"album_url"`
+}
+
+type CurrentInfo struct {
+	Title string `json:"title"`
+}
+
+type BandcampTrack struct {
+	Title    string  `json:"title"`
+	Artist   *string `json:"artist"`
+	TrackNum int     `json:"track_num"`
+	Duration float64 `json:"duration"`
+}
+
+type bandcampProvider struct{}
+
+func (bandcampProvider) Name() string { return "Bandcamp" }
+
+func (bandcampProvider) CanHandle(url string) bool {
+	if hostIs(urlHost(url), "bandcamp.com") {
+		return true
+	}
+	u, err := neturl.Parse(strings.TrimSpace(url))
+	return err == nil && (strings.HasPrefix(u.Path, "/album/") || strings.HasPrefix(u.Path, "/track/"))
+}
+
+// Fetch loads an album page. A track page is resolved to the album it is
+// part of, so the whole release is matched; a standalone single stays a
+// one-track release.
+func (p bandcampProvider) Fetch(ctx context.Context, url string) (*AlbumData, error) {
+	if p.IsDiscography(url) {
+		return nil, fmt.Errorf("%s is an artist or label page; pick one of its releases", url)
+	}
+	doc, err := fetchDocument(ctx, url)
+	if err != nil {
+		return nil, err
+	}
+	if albumURL := p.parentAlbumURL(doc, url); albumURL != "" {
+		log.Printf("Bandcamp track %s is part of %s", url, albumURL)
+		if doc, err = fetchDocument(ctx, albumURL); err != nil {
+			return nil, err
+		}
+	}
+	return p.parse(doc)
+}
+
+// parentAlbumURL returns the album a track page belongs to, or "" for album
+// pages and standalone tracks.
+func (p bandcampProvider) parentAlbumURL(doc *goquery.Document, pageURL string) string {
+	var item BandcampAlbum
+	data := doc.Find("script[data-tralbum]").AttrOr("data-tralbum", "")
+	if data == "" || json.Unmarshal([]byte(data), &item) != nil {
+		return ""
+	}
+	if item.ItemType != "track" || item.AlbumURL == nil || strings.TrimSpace(*item.AlbumURL) == "" {
+		return ""
+	}
+	return resolveURL(pageURL, *item.AlbumURL)
+}
+
+// parse reads the release from an album page. The page embeds everything in
+// the data-tralbum attribute.
+func (p bandcampProvider) parse(doc *goquery.Document) (*AlbumData, error) {
+	data := doc.Find("script[data-tralbum]").AttrOr("data-tralbum", "")
+	if data == "" {
+		return nil, fmt.Errorf("could not find album data on page")
+	}
+
+	var album BandcampAlbum
+	if err := json.Unmarshal([]byte(data), &album); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal album data: %w", err)
+	}
+
+	tracks := make([]AlbumTrack, 0, len(album.TrackInfo))
+	for _, bcTrack := range album.TrackInfo {
+		artist := ""
+		if bcTrack.Artist != nil {
+			artist = *bcTrack.Artist
+		}
+		tracks = append(tracks, AlbumTrack{
+			Title:            bcTrack.Title,
+			Artist:           artist,
+			TrackNum:         bcTrack.TrackNum,
+			TrackNumExplicit: true,
+			TrackID:          0,
+			Duration:         bcTrack.Duration,
+		})
+	}
+	return &AlbumData{
+		Artist: album.Artist,
+		Title:  album.Current.Title,
+		Tracks: tracks,
+		Source: p.Name(),
+	}, nil
+}
+
+func (p bandcampProvider) Search(ctx context.Context, query string) ([]ReleaseSummary, error) {
+	doc, err := fetchDocument(ctx, "https://bandcamp.com/search?item_type=a&q="+neturl.QueryEscape(query))
+	if err != nil {
+		return nil, err
+	}
+	return p.parseSearch(doc), nil
+}
+
+func (p bandcampProvider) parseSearch(doc *goquery.Document) []ReleaseSummary {
+	var results []ReleaseSummary
+	doc.Find("li.searchresult").Each(func(_ int, sel *goquery.Selection) {
+		if itemType := strings.TrimSpace(sel.Find(".itemtype").Text()); itemType != "" && !strings.EqualFold(itemType, "album") {
+			return
+		}
+		link := sel.Find(".heading a").First()
+		href := strings.TrimSpace(link.AttrOr("href", ""))
+		if href == "" {
+			return
+		}
+		if i := strings.Index(href, "?"); i >= 0 {
+			href = href[:i]
+		}
+		artist := strings.TrimSpace(reSpaces.ReplaceAllString(sel.Find(".subhead").Text(), " "))
+		artist = strings.TrimSpace(strings.TrimPrefix(artist, "by "))
+		trackCount := 0
+		if length := strings.TrimSpace(sel.Find(".length").Text()); length != "" {
+			fmt.Sscanf(length, "%d", &trackCount)
+		}
+		results = append(results, ReleaseSummary{
+			Provider:   p.Name(),
+			URL:        href,
+			Artist:     artist,
+			Title:      strings.TrimSpace(link.Text()),
+			TrackCount: trackCount,
+		})
+	})
+	return results
+}
+
+// IsDiscography reports whether url is an artist or label page rather than a
+// release: the site root, /music or /releases.
+func (bandcampProvider) IsDiscography(url string) bool {
+	u, err := neturl.Parse(strings.TrimSpace(url))
+	if err != nil {
+		return false
+	}
+	host := strings.ToLower(u.Hostname())
+	if !strings.HasSuffix(host, ".bandcamp.com") || host == "www.bandcamp.com" {
+		return false
+	}
+	switch strings.TrimSuffix(u.Path, "/") {
+	case "", "/music", "/releases":
+		return true
+	}
+	return false
+}
+
+func (p bandcampProvider) Discography(ctx context.Context, url string) ([]ReleaseSummary, error) {
+	doc, err := fetchDocument(ctx, url)
+	if err != nil {
+		return nil, err
+	}
+	releases := p.parseDiscography(doc, url)
+	if len(releases) == 0 {
+		return nil, fmt.Errorf("could not find any releases on %s", url)
+	}
+	return releases, nil
+}
+
+// parseDiscography lists the releases in an artist or label's music grid.
+// Only the first releases are rendered as HTML; the rest are in the grid's
+// data-client-items attribute. An artist with a single release gets that
+// album page instead of a grid.
+func (p bandcampProvider) parseDiscography(doc *goquery.Document, pageURL string) []ReleaseSummary {
+	bandName := cleanText(doc.Find("#band-name-location .title").First().Text())
+	if bandName == "" {
+		bandName = cleanText(doc.Find("meta[property='og:site_name']").AttrOr("content", ""))
+	}
+
+	var releases []ReleaseSummary
+	seen := map[string]bool{}
+	add := func(href, title, artist string) {
+		if href == "" || title == "" {
+			return
+		}
+		href = resolveURL(pageURL, href)
+		if i := strings.Index(href, "?"); i >= 0 {
+			href = href[:i]
+		}
+		if seen[href] {
+			return
+		}
+		seen[href] = true
+		releases = append(releases, ReleaseSummary{
+			Provider: p.Name(),
+			URL:      href,
+			Artist:   firstNonEmpty(artist, bandName),
+			Title:    title,
+		})
+	}
+
+	grid := doc.Find("#music-grid, ol.music-grid").First()
+	grid.Find("li.music-grid-item").Each(func(_ int, item *goquery.Selection) {
+		titleSel := item.Find(".title").First()
+		artist := cleanText(titleSel.Find(".artist-override").Text())
+		titleSel.Find(".artist-override").Remove()
+		add(item.Find("a").First().AttrOr("href", ""), cleanText(titleSel.Text()), artist)
+	})
+	if data := grid.AttrOr("data-client-items", ""); data != "" {
+		var items []struct {
+			Title   string `json:"title"`
+			Artist  string `json:"artist"`
+			PageURL string `json:"page_url"`
+		}
+		if err := json.Unmarshal([]byte(data), &items); err != nil {
+			log.Printf("Failed to parse Bandcamp client items: %v", err)
+		}
+		for _, item := range items {
+			add(item.PageURL, strings.TrimSpace(item.Title), strings.TrimSpace(item.Artist))
+		}
+	}
+
+	if len(releases) == 0 {
+		if album, err := p.parse(doc); err == nil && len(album.Tracks) > 0 {
+			url := doc.Find("meta[property='og:url']").AttrOr("content", pageURL)
+			releases = append(releases, ReleaseSummary{
+				Provider:   p.Name(),
+				URL:        url,
+				Artist:     album.Artist,
+				Title:      album.Title,
+				TrackCount: len(album.Tracks),
+			})
+		}
+	}
+	return releases
+}
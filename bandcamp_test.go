@@ -0,0 +1,91 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestBandcampParse(t *testing.T) {
+	album, err := bandcampProvider{}.parse(loadHTMLFixture(t, "bandcamp/album.html"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if album.Title != "Night Drive" || album.Artist != "Kora" || album.Source != "Bandcamp" {
+		t.Errorf("album = %q by %q from %q", album.Title, album.Artist, album.Source)
+	}
+	// Only tracks with their own artist carry one; the rest take the album's.
+	checkTracks(t, album, []AlbumTrack{
+		{Title: "Tunnel Lights", TrackNum: 1, Duration: 241.04},
+		{Title: "Mel - Overpass", TrackNum: 2, Duration: 305.5},
+		{Title: "Exit 9", Artist: "Kora & Dax", TrackNum: 3, Duration: 412},
+	})
+}
+
+func TestBandcampParentAlbumURL(t *testing.T) {
+	p := bandcampProvider{}
+	for _, tc := range []struct {
+		fixture, pageURL, want string
+	}{
+		{"bandcamp/track.html", "https://kora.bandcamp.com/track/overpass", "https://kora.bandcamp.com/album/night-drive"},
+		{"bandcamp/single.html", "https://kora.bandcamp.com/track/loose-end", ""},
+		{"bandcamp/album.html", "https://kora.bandcamp.com/album/night-drive", ""},
+	} {
+		if got := p.parentAlbumURL(loadHTMLFixture(t, tc.fixture), tc.pageURL); got != tc.want {
+			t.Errorf("%s: got %q, want %q", tc.fixture, got, tc.want)
+		}
+	}
+}
+
+func TestBandcampParseSearch(t *testing.T) {
+	got := bandcampProvider{}.parseSearch(loadHTMLFixture(t, "bandcamp/search.html"))
+	want := []ReleaseSummary{
+		{Provider: "Bandcamp", URL: "https://kora.bandcamp.com/album/night-drive", Artist: "Kora", Title: "Night Drive", TrackCount: 3},
+		{Provider: "Bandcamp", URL: "https://othernight.bandcamp.com/album/night-drive-2", Artist: "The Other Night", Title: "Night Drive 2"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %+v\nwant %+v", got, want)
+	}
+}
+
+func TestBandcampIsDiscography(t *testing.T) {
+	for url, want := range map[string]bool{
+		"https://coastline.bandcamp.com":                   true,
+		"https://coastline.bandcamp.com/":                  true,
+		"https://coastline.bandcamp.com/music":             true,
+		"https://coastline.bandcamp.com/releases/":         true,
+		"https://coastline.bandcamp.com/album/low-tide":    false,
+		"https://coastline.bandcamp.com/track/salt":        false,
+		"https://bandcamp.com/search?q=coastline":          false,
+		"https://www.bandcamp.com/":                        false,
+		"https://music.example.org/music":                  false,
+		"https://coastline.bandcamp.com.example.org/music": false,
+	} {
+		if got := (bandcampProvider{}).IsDiscography(url); got != want {
+			t.Errorf("%s: got %v, want %v", url, got, want)
+		}
+	}
+}
+
+func TestBandcampParseDiscography(t *testing.T) {
+	got := bandcampProvider{}.parseDiscography(loadHTMLFixture(t, "bandcamp/discography.html"), "https://coastline.bandcamp.com/music")
+	// The rendered grid comes first; client items fill in the rest without
+	// repeating it.
+	want := []ReleaseSummary{
+		{Provider: "Bandcamp", URL: "https://coastline.bandcamp.com/album/night-drive", Artist: "Kora", Title: "Night Drive"},
+		{Provider: "Bandcamp", URL: "https://coastline.bandcamp.com/album/harbour-lights", Artist: "Coastline Recordings", Title: "Harbour Lights"},
+		{Provider: "Bandcamp", URL: "https://coastline.bandcamp.com/album/low-tide-ep", Artist: "Coastline Recordings", Title: "Low Tide EP"},
+		{Provider: "Bandcamp", URL: "https://coastline.bandcamp.com/album/selected-works", Artist: "Marlow", Title: "Selected Works"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %+v\nwant %+v", got, want)
+	}
+
+	// An artist with a single release gets its album page.
+	got = bandcampProvider{}.parseDiscography(loadHTMLFixture(t, "bandcamp/album.html"), "https://kora.bandcamp.com/")
+	want = []ReleaseSummary{
+		{Provider: "Bandcamp", URL: "https://kora.bandcamp.com/album/night-drive", Artist: "Kora", Title: "Night Drive", TrackCount: 3},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("single release: got %+v\nwant %+v", got, want)
+	}
+}
@@ -0,0 +1,1012 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	neturl "net/url"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+type beatportProvider struct{}
+
+func (beatportProvider) Name() string { return "Beatport" }
+
+func (beatportProvider) CanHandle(url string) bool {
+	return hostIs(urlHost(url), "beatport.com")
+}
+
+// Fetch loads a release. A track URL is expanded to the release the track is
+// on; a chart URL is read as a playlist of tracks from many releases.
+func (p beatportProvider) Fetch(ctx context.Context, url string) (*AlbumData, error) {
+	kind, id := parseBeatportURL(url)
+	doc, err := fetchDocument(ctx, url)
+	if err != nil {
+		return nil, err
+	}
+	switch kind {
+	case beatportTrackPage:
+		releaseURL := findBeatportTrackRelease(doc, id)
+		if releaseURL == "" {
+			return nil, fmt.Errorf("could not find the release of Beatport track %d", id)
+		}
+		log.Printf("Beatport track %d is on %s", id, releaseURL)
+		if doc, err = fetchDocument(ctx, releaseURL); err != nil {
+			return nil, err
+		}
+		return p.parse(doc, releaseURL)
+	case beatportChartPage:
+		return p.parseChart(doc, id)
+	}
+	return p.parse(doc, url)
+}
+
+// parse reads a release page. Beatport has changed its markup several times,
+// so JSON-LD, the Next.js page data and the older data-json spans are tried
+// in turn.
+func (p beatportProvider) parse(doc *goquery.Document, url string) (*AlbumData, error) {
+	album := AlbumData{Source: p.Name()}
+	releaseID := extractBeatportReleaseID(url)
+
+	if ogTitle := strings.TrimSpace(doc.Find("meta[property='og:title']").AttrOr("content", "")); ogTitle != "" {
+		title, artist := parseBeatportMetaTitle(ogTitle)
+		if album.Title == "" {
+			album.Title = title
+		}
+		if album.Artist == "" {
+			album.Artist = artist
+		}
+	}
+
+	album.Label, album.CatalogNumber = parseBeatportReleaseInfo(doc, releaseID)
+
+	if tracks, title, artist := parseJSONLDRelease(doc); len(tracks) > 0 {
+		if album.Title == "" && title != "" {
+			album.Title = title
+		}
+		if album.Artist == "" && artist != "" {
+			album.Artist = artist
+		}
+		album.Tracks = tracks
+		return &album, nil
+	}
+
+	tracks, orderMap := parseBeatportNextData(doc, releaseID)
+	if len(tracks) > 0 {
+		album.Tracks = tracks
+		return &album, nil
+	}
+
+	if tracks := parseBeatportDataJSON(doc, releaseID, orderMap); len(tracks) > 0 {
+		album.Tracks = tracks
+		return &album, nil
+	}
+
+	return nil, fmt.Errorf("could not find Beatport track data on page")
+}
+
+func (p beatportProvider) Search(ctx context.Context, query string) ([]ReleaseSummary, error) {
+	doc, err := fetchDocument(ctx, "https://www.beatport.com/search/releases?q="+neturl.QueryEscape(query))
+	if err != nil {
+		return nil, err
+	}
+	return p.parseSearch(doc), nil
+}
+
+// parseSearch collects release objects from the search page's Next.js data.
+func (p beatportProvider) parseSearch(doc *goquery.Document) []ReleaseSummary {
+	raw := strings.TrimSpace(doc.Find("script#__NEXT_DATA__").Text())
+	if raw == "" {
+		return nil
+	}
+	var data interface{}
+	if err := json.Unmarshal([]byte(raw), &data); err != nil {
+		return nil
+	}
+	var results []ReleaseSummary
+	seen := map[int]bool{}
+	var walk func(value interface{})
+	walk = func(value interface{}) {
+		switch v := value.(type) {
+		case map[string]interface{}:
+			if summary, id, ok := p.releaseSummaryFromObj(v); ok && !seen[id] {
+				seen[id] = true
+				results = append(results, summary)
+				return
+			}
+			for _, key := range sortedKeys(v) {
+				walk(v[key])
+			}
+		case []interface{}:
+			for _, item := range v {
+				walk(item)
+			}
+		}
+	}
+	walk(data)
+	return results
+}
+
+func (p beatportProvider) releaseSummaryFromObj(obj map[string]interface{}) (ReleaseSummary, int, bool) {
+	id := parseIntFromAny(obj["release_id"])
+	name := getStringFromMap(obj, "release_name")
+	if id == 0 || name == "" {
+		// Objects nested under a "release" key use plain id/name.
+		if _, hasTracks := obj["track_count"]; !hasTracks {
+			return ReleaseSummary{}, 0, false
+		}
+		id = parseIntFromAny(obj["id"])
+		name = getStringFromMap(obj, "name")
+	}
+	if id == 0 || name == "" {
+		return ReleaseSummary{}, 0, false
+	}
+	slug := getStringFromMap(obj, "slug", "release_slug")
+	if slug == "" {
+		slug = strings.Trim(reNonWordSpace.ReplaceAllString(strings.ToLower(name), "-"), "-")
+	}
+	label := ""
+	if l, ok := obj["label"].(map[string]interface{}); ok {
+		label = getStringFromMap(l, "label_name", "name")
+	}
+	return ReleaseSummary{
+		Provider:   p.Name(),
+		URL:        fmt.Sprintf("https://www.beatport.com/release/%s/%d", slug, id),
+		Artist:     beatportArtists(obj["artists"]),
+		Title:      name,
+		Label:      label,
+		TrackCount: parseIntFromAny(obj["track_count"], obj["tracks_count"]),
+	}, id, true
+}
+
+// beatportArtists reads an artist list that may use artist_name (search
+// results) or name (release pages).
+func beatportArtists(value interface{}) string {
+	if list, ok := value.([]interface{}); ok {
+		names := make([]string, 0, len(list))
+		for _, item := range list {
+			if m, ok := item.(map[string]interface{}); ok {
+				if name := getStringFromMap(m, "artist_name", "name"); name != "" {
+					names = append(names, name)
+				}
+			}
+		}
+		return strings.Join(names, ", ")
+	}
+	return parseArtistsField(value)
+}
+
+// sortedKeys returns the keys of a JSON object in order. The page data is
+// searched in this order, so when several nested objects match, the same one
+// is found on every run.
+func sortedKeys(obj map[string]interface{}) []string {
+	keys := make([]string, 0, len(obj))
+	for key := range obj {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func parseBeatportMetaTitle(s string) (string, string) {
+	s = strings.TrimSpace(strings.ReplaceAll(s, " on Beatport", ""))
+	if strings.Contains(s, " by ") {
+		parts := strings.SplitN(s, " by ", 2)
+		return strings.TrimSpace(parts[0]), strings.TrimSpace(parts[1])
+	}
+	return strings.TrimSpace(s), ""
+}
+
+// Beatport page kinds, named after the first path segment of their URLs.
+const (
+	beatportReleasePage = "release"
+	beatportTrackPage   = "track"
+	beatportChartPage   = "chart"
+)
+
+// parseBeatportURL returns the page kind and ID of a Beatport URL such as
+// /release/<slug>/<id>, /track/<slug>/<id> or /chart/<slug>/<id>. Localised
+// URLs (/de/release/...) are accepted too.
+func parseBeatportURL(url string) (string, int) {
+	u, err := neturl.Parse(strings.TrimSpace(url))
+	if err != nil {
+		return "", 0
+	}
+	parts := strings.Split(strings.Trim(u.Path, "/"), "/")
+	for i, part := range parts {
+		switch part {
+		case beatportReleasePage, beatportTrackPage, beatportChartPage:
+			// The ID follows the slug; some short links omit the slug.
+			for _, j := range []int{i + 2, i + 1} {
+				if j < len(parts) {
+					if n, err := strconv.Atoi(parts[j]); err == nil {
+						return part, n
+					}
+				}
+			}
+			return part, 0
+		}
+	}
+	return "", 0
+}
+
+// extractBeatportReleaseID returns the release ID of a release URL, and 0 for
+// any other page: track and chart IDs are not release IDs.
+func extractBeatportReleaseID(url string) int {
+	kind, id := parseBeatportURL(url)
+	if kind != beatportReleasePage {
+		return 0
+	}
+	return id
+}
+
+// findBeatportTrackRelease returns the release URL of the track on a track
+// page, from the page data or, failing that, the first release link.
+func findBeatportTrackRelease(doc *goquery.Document, trackID int) string {
+	raw := strings.TrimSpace(doc.Find("script#__NEXT_DATA__").Text())
+	var data interface{}
+	if raw != "" && json.Unmarshal([]byte(raw), &data) == nil {
+		if release := findBeatportTrackReleaseObject(data, trackID); release != nil {
+			id := parseIntFromAny(release["id"])
+			slug := getStringFromMap(release, "slug")
+			if slug == "" {
+				slug = strings.Trim(reNonWordSpace.ReplaceAllString(strings.ToLower(getStringFromMap(release, "name")), "-"), "-")
+			}
+			if id != 0 && slug != "" {
+				return fmt.Sprintf("https://www.beatport.com/release/%s/%d", slug, id)
+			}
+		}
+	}
+	href := doc.Find("a[href*='/release/']").First().AttrOr("href", "")
+	if href == "" {
+		return ""
+	}
+	return resolveURL("https://www.beatport.com/", href)
+}
+
+// findBeatportTrackReleaseObject finds the release object nested in the track
+// with the given ID.
+func findBeatportTrackReleaseObject(value interface{}, trackID int) map[string]interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		if trackID != 0 && parseIntFromAny(v["id"]) == trackID {
+			if release, ok := v["release"].(map[string]interface{}); ok && parseIntFromAny(release["id"]) != 0 {
+				return release
+			}
+		}
+		for _, key := range sortedKeys(v) {
+			if found := findBeatportTrackReleaseObject(v[key], trackID); found != nil {
+				return found
+			}
+		}
+	case []interface{}:
+		for _, item := range v {
+			if found := findBeatportTrackReleaseObject(item, trackID); found != nil {
+				return found
+			}
+		}
+	}
+	return nil
+}
+
+// parseChart reads a chart as a playlist: tracks from many releases, numbered
+// by their chart position. The chart name stands in for the album title.
+func (p beatportProvider) parseChart(doc *goquery.Document, chartID int) (*AlbumData, error) {
+	album := AlbumData{Artist: "Various Artists", Source: p.Name()}
+	if ogTitle := strings.TrimSpace(doc.Find("meta[property='og:title']").AttrOr("content", "")); ogTitle != "" {
+		album.Title, _ = parseBeatportMetaTitle(ogTitle)
+	}
+
+	raw := strings.TrimSpace(doc.Find("script#__NEXT_DATA__").Text())
+	var data interface{}
+	if raw != "" && json.Unmarshal([]byte(raw), &data) == nil {
+		if results := findBeatportChartResults(data, chartID); len(results) > 0 {
+			album.Tracks = buildBeatportTracksFromOrderedResults(results, nil)
+		} else {
+			album.Tracks = findTrackListInJSON(data, 0, nil)
+		}
+	}
+	if len(album.Tracks) == 0 {
+		tracks, title, _ := parseJSONLDRelease(doc)
+		album.Tracks = tracks
+		album.Title = firstNonEmpty(album.Title, title)
+	}
+	if len(album.Tracks) == 0 {
+		return nil, fmt.Errorf("could not find Beatport chart tracks on page")
+	}
+	// Chart order is the playlist order, whatever the tracks' positions on
+	// their own releases.
+	for i := range album.Tracks {
+		album.Tracks[i].TrackNum = i + 1
+		album.Tracks[i].TrackNumExplicit = true
+	}
+	return &album, nil
+}
+
+// findBeatportChartResults returns the track results of the chart's tracks
+// query in the dehydrated Next.js state.
+func findBeatportChartResults(data interface{}, chartID int) []interface{} {
+	root, _ := data.(map[string]interface{})
+	props, _ := root["props"].(map[string]interface{})
+	pageProps, _ := props["pageProps"].(map[string]interface{})
+	dehydrated, _ := pageProps["dehydratedState"].(map[string]interface{})
+	queries, _ := dehydrated["queries"].([]interface{})
+	for _, q := range queries {
+		qm, ok := q.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		qk, ok := qm["queryKey"].([]interface{})
+		if !ok || len(qk) < 2 {
+			continue
+		}
+		if key, ok := qk[0].(string); !ok || !strings.Contains(key, "track") {
+			continue
+		}
+		params, ok := qk[1].(map[string]interface{})
+		if !ok || parseIntFromAny(params["chart_id"], params["chartId"], params["id"]) != chartID {
+			continue
+		}
+		state, _ := qm["state"].(map[string]interface{})
+		dataNode, _ := state["data"].(map[string]interface{})
+		if results, _ := dataNode["results"].([]interface{}); len(results) > 0 {
+			return results
+		}
+	}
+	return nil
+}
+
+// parseJSONLDRelease reads the largest schema.org MusicAlbum/MusicRelease
+// embedded as JSON-LD. Several stores publish one.
+func parseJSONLDRelease(doc *goquery.Document) ([]AlbumTrack, string, string) {
+	var bestTracks []AlbumTrack
+	var bestTitle string
+	var bestArtist string
+
+	doc.Find("script[type='application/ld+json']").Each(func(_ int, sel *goquery.Selection) {
+		raw := strings.TrimSpace(sel.Text())
+		if raw == "" {
+			return
+		}
+		var data interface{}
+		if err := json.Unmarshal([]byte(raw), &data); err != nil {
+			return
+		}
+		tracks, title, artist := parseLDData(data)
+		if len(tracks) > len(bestTracks) {
+			bestTracks = tracks
+			bestTitle = title
+			bestArtist = artist
+		}
+	})
+
+	return bestTracks, bestTitle, bestArtist
+}
+
+func parseLDData(data interface{}) ([]AlbumTrack, string, string) {
+	switch v := data.(type) {
+	case []interface{}:
+		var bestTracks []AlbumTrack
+		var bestTitle string
+		var bestArtist string
+		for _, item := range v {
+			tracks, title, artist := parseLDData(item)
+			if len(tracks) > len(bestTracks) {
+				bestTracks = tracks
+				bestTitle = title
+				bestArtist = artist
+			}
+		}
+		return bestTracks, bestTitle, bestArtist
+	case map[string]interface{}:
+		if graph, ok := v["@graph"]; ok {
+			return parseLDData(graph)
+		}
+		typ := getStringFromMap(v, "@type")
+		if typ == "MusicAlbum" || typ == "MusicRelease" || typ == "MusicPlaylist" {
+			title := getStringFromMap(v, "name", "title")
+			artist := parseArtistsField(v["byArtist"])
+			if artist == "" {
+				artist = parseArtistsField(v["artist"])
+			}
+			tracks := parseLDTracks(v["track"])
+			return tracks, title, artist
+		}
+	}
+	return nil, "", ""
+}
+
+func parseLDTracks(value interface{}) []AlbumTrack {
+	arr, ok := value.([]interface{})
+	if !ok || len(arr) == 0 {
+		return nil
+	}
+	tracks := make([]AlbumTrack, 0, len(arr))
+	for i, item := range arr {
+		m, ok := item.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		title := getStringFromMap(m, "name", "title")
+		artist := parseArtistsField(m["byArtist"])
+		if artist == "" {
+			artist = parseArtistsField(m["artist"])
+		}
+		trackNum, explicit := extractTrackNumberFromObj(m)
+		if trackNum == 0 {
+			trackNum = i + 1
+		}
+		if title != "" {
+			tracks = append(tracks, AlbumTrack{
+				Title:            title,
+				Artist:           artist,
+				TrackNum:         trackNum,
+				TrackNumExplicit: explicit,
+				Duration:         extractDurationFromObj(m),
+			})
+		}
+	}
+	return tracks
+}
+
+func parseBeatportDataJSON(doc *goquery.Document, releaseID int, orderMap map[int]int) []AlbumTrack {
+	var tracks []AlbumTrack
+	doc.Find("span[data-json]").Each(func(_ int, sel *goquery.Selection) {
+		data := strings.TrimSpace(sel.AttrOr("data-json", ""))
+		if data == "" {
+			return
+		}
+		var obj map[string]interface{}
+		if err := json.Unmarshal([]byte(data), &obj); err != nil {
+			return
+		}
+		if releaseID != 0 {
+			if objRelease := extractReleaseIDFromObj(obj); objRelease != 0 && objRelease != releaseID {
+				return
+			}
+		}
+		trackID := extractTrackIDFromObj(obj)
+		title := getStringFromMap(obj, "title", "name")
+		artist := parseArtistsField(obj["artists"])
+		mixName := getStringFromMap(obj, "mixName", "mix_name")
+		if mixName != "" && !strings.Contains(strings.ToLower(title), strings.ToLower(mixName)) {
+			title = strings.TrimSpace(title) + " (" + mixName + ")"
+		}
+		trackNum, explicit := extractTrackNumberFromObj(obj)
+		if trackNum == 0 && orderMap != nil && trackID != 0 {
+			if n, ok := orderMap[trackID]; ok {
+				trackNum = n
+				explicit = true
+			}
+		}
+		if trackNum == 0 {
+			trackNum = len(tracks) + 1
+		}
+		if title != "" {
+			tracks = append(tracks, AlbumTrack{
+				Title:            title,
+				Artist:           artist,
+				TrackNum:         trackNum,
+				TrackNumExplicit: explicit,
+				TrackID:          trackID,
+				Duration:         extractDurationFromObj(obj),
+			})
+		}
+	})
+	return tracks
+}
+
+func parseBeatportNextData(doc *goquery.Document, releaseID int) ([]AlbumTrack, map[int]int) {
+	raw := strings.TrimSpace(doc.Find("script#__NEXT_DATA__").Text())
+	if raw == "" {
+		return nil, nil
+	}
+	var data interface{}
+	if err := json.Unmarshal([]byte(raw), &data); err != nil {
+		return nil, nil
+	}
+	orderMap := findBeatportReleaseTrackOrder(data, releaseID)
+	if tracks := extractBeatportTracksFromDehydrated(data, releaseID, orderMap); len(tracks) > 0 {
+		return tracks, orderMap
+	}
+	return findTrackListInJSON(data, releaseID, orderMap), orderMap
+}
+
+func parseBeatportReleaseInfo(doc *goquery.Document, releaseID int) (string, string) {
+	raw := strings.TrimSpace(doc.Find("script#__NEXT_DATA__").Text())
+	if raw == "" || releaseID == 0 {
+		return "", ""
+	}
+	var data interface{}
+	if err := json.Unmarshal([]byte(raw), &data); err != nil {
+		return "", ""
+	}
+	release := findBeatportReleaseObject(data, releaseID)
+	if release == nil {
+		return "", ""
+	}
+	label := parseArtistsField(release["label"])
+	catno := getStringFromMap(release, "catalog_number", "catalogNumber")
+	return label, catno
+}
+
+func findBeatportReleaseObject(value interface{}, releaseID int) map[string]interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		if id := parseIntFromAny(v["id"]); id == releaseID {
+			if _, ok := v["catalog_number"]; ok {
+				return v
+			}
+			if _, ok := v["label"]; ok {
+				return v
+			}
+		}
+		for _, key := range sortedKeys(v) {
+			if found := findBeatportReleaseObject(v[key], releaseID); found != nil {
+				return found
+			}
+		}
+	case []interface{}:
+		for _, item := range v {
+			if found := findBeatportReleaseObject(item, releaseID); found != nil {
+				return found
+			}
+		}
+	}
+	return nil
+}
+
+func findTrackListInJSON(data interface{}, releaseID int, orderMap map[int]int) []AlbumTrack {
+	var candidates [][]map[string]interface{}
+	collectTrackArrays(data, &candidates)
+	if len(candidates) == 0 {
+		return nil
+	}
+	best := scoreTrackCandidate(candidates[0], releaseID, orderMap)
+	for _, c := range candidates[1:] {
+		cand := scoreTrackCandidate(c, releaseID, orderMap)
+		if cand.Score > best.Score {
+			best = cand
+		}
+	}
+	ordered := orderTrackObjects(best.Tracks, orderMap)
+	tracks := make([]AlbumTrack, 0, len(ordered))
+	for i, obj := range ordered {
+		trackID := extractTrackIDFromObj(obj)
+		title := getStringFromMap(obj, "name", "title")
+		artist := parseArtistsField(obj["artists"])
+		if artist == "" {
+			artist = parseArtistsField(obj["artist"])
+		}
+		mixName := getStringFromMap(obj, "mixName", "mix_name")
+		if mixName != "" && !strings.Contains(strings.ToLower(title), strings.ToLower(mixName)) {
+			title = strings.TrimSpace(title) + " (" + mixName + ")"
+		}
+		trackNum, explicit := extractTrackNumberFromObj(obj)
+		if trackNum == 0 && orderMap != nil {
+			if trackID != 0 {
+				if n, ok := orderMap[trackID]; ok {
+					trackNum = n
+					explicit = true
+				}
+			}
+		}
+		if trackNum == 0 {
+			trackNum = i + 1
+		}
+		if title != "" {
+			tracks = append(tracks, AlbumTrack{
+				Title:            title,
+				Artist:           artist,
+				TrackNum:         trackNum,
+				TrackNumExplicit: explicit,
+				TrackID:          trackID,
+				Duration:         extractDurationFromObj(obj),
+			})
+		}
+	}
+	return tracks
+}
+
+func extractBeatportTracksFromDehydrated(data interface{}, releaseID int, orderMap map[int]int) []AlbumTrack {
+	root, ok := data.(map[string]interface{})
+	if !ok {
+		return nil
+	}
+	props, _ := root["props"].(map[string]interface{})
+	pageProps, _ := props["pageProps"].(map[string]interface{})
+	dehydrated, _ := pageProps["dehydratedState"].(map[string]interface{})
+	queries, _ := dehydrated["queries"].([]interface{})
+	if len(queries) == 0 {
+		return nil
+	}
+	for _, q := range queries {
+		qm, ok := q.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		qk, ok := qm["queryKey"].([]interface{})
+		if !ok || len(qk) < 2 {
+			continue
+		}
+		if key, ok := qk[0].(string); !ok || key != "tracks" {
+			continue
+		}
+		params, ok := qk[1].(map[string]interface{})
+		if !ok {
+			continue
+		}
+		if releaseID != 0 {
+			if rid := parseIntFromAny(params["release_id"], params["releaseId"]); rid != releaseID {
+				continue
+			}
+		}
+		state, _ := qm["state"].(map[string]interface{})
+		dataNode, _ := state["data"].(map[string]interface{})
+		results, _ := dataNode["results"].([]interface{})
+		if len(results) == 0 {
+			continue
+		}
+		return buildBeatportTracksFromOrderedResults(results, orderMap)
+	}
+	return nil
+}
+
+func buildBeatportTracksFromOrderedResults(results []interface{}, orderMap map[int]int) []AlbumTrack {
+	objs := make([]map[string]interface{}, 0, len(results))
+	for _, item := range results {
+		if m, ok := item.(map[string]interface{}); ok {
+			objs = append(objs, m)
+		}
+	}
+	if len(objs) == 0 {
+		return nil
+	}
+	tracks := make([]AlbumTrack, 0, len(objs))
+	for i, obj := range objs {
+		trackID := extractTrackIDFromObj(obj)
+		title := getStringFromMap(obj, "name", "title")
+		artist := parseArtistsField(obj["artists"])
+		if artist == "" {
+			artist = parseArtistsField(obj["artist"])
+		}
+		mixName := getStringFromMap(obj, "mixName", "mix_name")
+		if mixName != "" && !strings.Contains(strings.ToLower(title), strings.ToLower(mixName)) {
+			title = strings.TrimSpace(title) + " (" + mixName + ")"
+		}
+		trackNum, explicit := extractTrackNumberFromObj(obj)
+		if trackNum == 0 && trackID != 0 {
+			if n, ok := orderMap[trackID]; ok {
+				trackNum = n
+				explicit = true
+			}
+		}
+		if trackNum == 0 {
+			trackNum = i + 1
+		}
+		if title != "" {
+			tracks = append(tracks, AlbumTrack{
+				Title:            title,
+				Artist:           artist,
+				TrackNum:         trackNum,
+				TrackNumExplicit: explicit,
+				TrackID:          trackID,
+				Duration:         extractDurationFromObj(obj),
+			})
+		}
+	}
+	return tracks
+}
+
+type trackListCandidate struct {
+	Tracks []map[string]interface{}
+	Score  int
+}
+
+func scoreTrackCandidate(arr []map[string]interface{}, releaseID int, orderMap map[int]int) trackListCandidate {
+	score := len(arr)
+	nums := make([]int, 0, len(arr))
+	artistCount := 0
+	releaseMatch := 0
+	releaseSeen := 0
+	orderMatches := 0
+	for _, obj := range arr {
+		if num, _ := extractTrackNumberFromObj(obj); num > 0 {
+			nums = append(nums, num)
+		}
+		if _, ok := obj["artists"]; ok {
+			artistCount++
+		} else if _, ok := obj["artist"]; ok {
+			artistCount++
+		}
+		if releaseID != 0 {
+			if objRelease := extractReleaseIDFromObj(obj); objRelease != 0 {
+				releaseSeen++
+				if objRelease == releaseID {
+					releaseMatch++
+				}
+			}
+		}
+		if orderMap != nil {
+			if id := extractTrackIDFromObj(obj); id != 0 {
+				if _, ok := orderMap[id]; ok {
+					orderMatches++
+				}
+			}
+		}
+	}
+	score += artistCount
+	if len(nums) > 0 {
+		score += len(nums) * 3
+		if isSequentialFromOne(nums, len(arr)) {
+			score += 200
+		} else if isSequentialFromOne(nums, len(nums)) {
+			score += 100
+		}
+	}
+	if releaseID != 0 && releaseSeen > 0 {
+		if releaseMatch == releaseSeen && releaseMatch >= len(arr)/2 {
+			score += 1000
+		} else if releaseMatch > 0 {
+			score += releaseMatch * 10
+		}
+	}
+	if orderMatches > 0 {
+		score += orderMatches * 5
+		if orderMatches >= len(arr)/2 {
+			score += 50
+		}
+	}
+	return trackListCandidate{Tracks: arr, Score: score}
+}
+
+func isSequentialFromOne(nums []int, expected int) bool {
+	if len(nums) == 0 {
+		return false
+	}
+	seen := make(map[int]struct{}, len(nums))
+	for _, n := range nums {
+		if n <= 0 {
+			return false
+		}
+		seen[n] = struct{}{}
+	}
+	if len(seen) != len(nums) {
+		return false
+	}
+	if expected <= 0 {
+		expected = len(nums)
+	}
+	for i := 1; i <= expected; i++ {
+		if _, ok := seen[i]; !ok {
+			return false
+		}
+	}
+	return true
+}
+
+func orderTrackObjects(arr []map[string]interface{}, orderMap map[int]int) []map[string]interface{} {
+	if len(arr) == 0 {
+		return arr
+	}
+	if orderMap != nil && len(orderMap) > 0 {
+		ordered := make([]map[string]interface{}, 0, len(arr))
+		used := make([]bool, len(arr))
+		for i := 1; i <= len(orderMap); i++ {
+			for idx, obj := range arr {
+				if used[idx] {
+					continue
+				}
+				if id := extractTrackIDFromObj(obj); id != 0 {
+					if n, ok := orderMap[id]; ok && n == i {
+						ordered = append(ordered, obj)
+						used[idx] = true
+						break
+					}
+				}
+			}
+		}
+		if len(ordered) >= len(arr)/2 {
+			for idx, obj := range arr {
+				if !used[idx] {
+					ordered = append(ordered, obj)
+				}
+			}
+			return ordered
+		}
+	}
+	nums := make([]int, 0, len(arr))
+	countWithNum := 0
+	for _, obj := range arr {
+		if n, _ := extractTrackNumberFromObj(obj); n > 0 {
+			nums = append(nums, n)
+			countWithNum++
+		}
+	}
+	if countWithNum == 0 {
+		return arr
+	}
+	if !isSequentialFromOne(nums, len(arr)) && countWithNum < len(arr)/2 {
+		return arr
+	}
+	ordered := make([]map[string]interface{}, 0, len(arr))
+	used := make([]bool, len(arr))
+	for i := 1; i <= len(arr); i++ {
+		found := false
+		for idx, obj := range arr {
+			if used[idx] {
+				continue
+			}
+			if n, _ := extractTrackNumberFromObj(obj); n == i {
+				ordered = append(ordered, obj)
+				used[idx] = true
+				found = true
+				break
+			}
+		}
+		if !found {
+			break
+		}
+	}
+	if len(ordered) == len(arr) {
+		return ordered
+	}
+	return arr
+}
+
+func collectTrackArrays(value interface{}, out *[][]map[string]interface{}) {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for _, key := range sortedKeys(v) {
+			collectTrackArrays(v[key], out)
+		}
+	case []interface{}:
+		if len(v) > 0 {
+			allTracks := true
+			arr := make([]map[string]interface{}, 0, len(v))
+			for _, item := range v {
+				obj, ok := item.(map[string]interface{})
+				if !ok || !isTrackLike(obj) {
+					allTracks = false
+					break
+				}
+				arr = append(arr, obj)
+			}
+			if allTracks {
+				*out = append(*out, arr)
+			}
+		}
+		for _, nested := range v {
+			collectTrackArrays(nested, out)
+		}
+	}
+}
+
+func isTrackLike(obj map[string]interface{}) bool {
+	title := getStringFromMap(obj, "name", "title")
+	if title == "" {
+		return false
+	}
+	if _, ok := obj["artists"]; ok {
+		return true
+	}
+	if _, ok := obj["artist"]; ok {
+		return true
+	}
+	return false
+}
+
+func extractReleaseIDFromObj(obj map[string]interface{}) int {
+	if v, ok := obj["releaseId"]; ok {
+		return parseIntFromAny(v)
+	}
+	if v, ok := obj["release_id"]; ok {
+		return parseIntFromAny(v)
+	}
+	if v, ok := obj["release"]; ok {
+		if m, ok := v.(map[string]interface{}); ok {
+			return parseIntFromAny(m["id"], m["releaseId"], m["release_id"])
+		}
+	}
+	return 0
+}
+
+func extractTrackIDFromObj(obj map[string]interface{}) int {
+	return parseIntFromAny(obj["id"], obj["trackId"], obj["track_id"])
+}
+
+func parseBeatportTrackIDFromURL(s string) int {
+	if s == "" {
+		return 0
+	}
+	trimmed := strings.TrimRight(s, "/")
+	if trimmed == "" {
+		return 0
+	}
+	parts := strings.Split(trimmed, "/")
+	last := parts[len(parts)-1]
+	if n, err := strconv.Atoi(last); err == nil {
+		return n
+	}
+	return 0
+}
+
+func extractTrackIDFromAny(value interface{}) int {
+	switch v := value.(type) {
+	case string:
+		return parseBeatportTrackIDFromURL(v)
+	case map[string]interface{}:
+		if id := extractTrackIDFromObj(v); id != 0 {
+			return id
+		}
+		if url, ok := v["url"].(string); ok {
+			return parseBeatportTrackIDFromURL(url)
+		}
+	case int:
+		return v
+	case int64:
+		return int(v)
+	case float64:
+		if v > 0 {
+			return int(v)
+		}
+	case json.Number:
+		if n, err := v.Int64(); err == nil {
+			return int(n)
+		}
+	}
+	return 0
+}
+
+func buildOrderMapFromTrackList(value interface{}) map[int]int {
+	arr, ok := value.([]interface{})
+	if !ok || len(arr) == 0 {
+		return nil
+	}
+	order := make(map[int]int, len(arr))
+	pos := 1
+	for _, item := range arr {
+		if id := extractTrackIDFromAny(item); id != 0 {
+			if _, exists := order[id]; !exists {
+				order[id] = pos
+				pos++
+			}
+		}
+	}
+	if len(order) == 0 {
+		return nil
+	}
+	return order
+}
+
+func findBeatportReleaseTrackOrder(value interface{}, releaseID int) map[int]int {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		if releaseID != 0 {
+			if id := parseIntFromAny(v["id"]); id == releaseID {
+				if tracksVal, ok := v["tracks"]; ok {
+					if order := buildOrderMapFromTrackList(tracksVal); len(order) > 0 {
+						return order
+					}
+				}
+			}
+		}
+		for _, key := range sortedKeys(v) {
+			if order := findBeatportReleaseTrackOrder(v[key], releaseID); len(order) > 0 {
+				return order
+			}
+		}
+	case []interface{}:
+		for _, item := range v {
+			if order := findBeatportReleaseTrackOrder(item, releaseID); len(order) > 0 {
+				return order
+			}
+		}
+	}
+	return nil
+}
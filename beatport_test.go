@@ -0,0 +1,119 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+func TestBeatportParse(t *testing.T) {
+	album, err := beatportProvider{}.parse(loadHTMLFixture(t, "beatport/release.html"), "https://www.beatport.com/release/harbour-lights/4401")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if album.Title != "Harbour Lights" || album.Artist != "Marlow" {
+		t.Errorf("album = %q by %q", album.Title, album.Artist)
+	}
+	if album.Label != "Coastline Recordings" || album.CatalogNumber != "COAST020" {
+		t.Errorf("label = %q, catalog number = %q", album.Label, album.CatalogNumber)
+	}
+	// The query lists the tracks out of order; the release's own track list
+	// numbers them.
+	checkTracks(t, album, []AlbumTrack{
+		{Title: "Salt (Original Mix)", Artist: "Marlow", TrackNum: 2, Duration: 340},
+		{Title: "Harbour Lights (Extended Mix)", Artist: "Marlow, Ina Vale", TrackNum: 1, Duration: 372},
+	})
+}
+
+func TestBeatportParseChart(t *testing.T) {
+	album, err := beatportProvider{}.parseChart(loadHTMLFixture(t, "beatport/chart.html"), 900)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if album.Title != "Summer Deep" || album.Artist != "Various Artists" {
+		t.Errorf("album = %q by %q", album.Title, album.Artist)
+	}
+	// Chart order wins over the tracks' numbers on their own releases, and
+	// the release query on the same page is not mistaken for the chart.
+	checkTracks(t, album, []AlbumTrack{
+		{Title: "Sonar (Extended Mix)", Artist: "Deepline", TrackNum: 1, Duration: 465},
+		{Title: "Harbour Lights (Extended Mix)", Artist: "Marlow, Ina Vale", TrackNum: 2, Duration: 372},
+		{Title: "Keel (Original Mix)", Artist: "Hull", TrackNum: 3, Duration: 481},
+	})
+}
+
+func TestBeatportTrackRelease(t *testing.T) {
+	doc := loadHTMLFixture(t, "beatport/track.html")
+	if got, want := findBeatportTrackRelease(doc, 17002), "https://www.beatport.com/release/harbour-lights/4401"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+
+	// Without page data the first release link is used.
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(`<p>From <a href="/release/harbour-lights/4401">Harbour Lights</a></p>`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := findBeatportTrackRelease(doc, 17002), "https://www.beatport.com/release/harbour-lights/4401"; got != want {
+		t.Errorf("link fallback: got %q, want %q", got, want)
+	}
+}
+
+func TestBeatportParseSearch(t *testing.T) {
+	got := beatportProvider{}.parseSearch(loadHTMLFixture(t, "beatport/search.html"))
+	want := []ReleaseSummary{
+		{Provider: "Beatport", URL: "https://www.beatport.com/release/harbour-lights/4401", Artist: "Marlow", Title: "Harbour Lights", Label: "Coastline Recordings", TrackCount: 2},
+		{Provider: "Beatport", URL: "https://www.beatport.com/release/harbour-lights-remixes-ep/4402", Artist: "Marlow, Sam Ortega", Title: "Harbour Lights (Remixes)", Label: "Coastline Recordings", TrackCount: 4},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %+v\nwant %+v", got, want)
+	}
+}
+
+func TestParseBeatportURL(t *testing.T) {
+	for _, tc := range []struct {
+		url  string
+		kind string
+		id   int
+	}{
+		{"https://www.beatport.com/release/harbour-lights/4401", beatportReleasePage, 4401},
+		{"https://www.beatport.com/de/release/harbour-lights/4401/", beatportReleasePage, 4401},
+		{"https://www.beatport.com/release/4401", beatportReleasePage, 4401},
+		{"https://www.beatport.com/track/harbour-lights/17002", beatportTrackPage, 17002},
+		{"https://www.beatport.com/chart/summer-deep/900?page=2", beatportChartPage, 900},
+		{"https://www.beatport.com/release/harbour-lights", beatportReleasePage, 0},
+		{"https://www.beatport.com/artist/marlow/1000", "", 0},
+	} {
+		kind, id := parseBeatportURL(tc.url)
+		if kind != tc.kind || id != tc.id {
+			t.Errorf("%s: got %q %d, want %q %d", tc.url, kind, id, tc.kind, tc.id)
+		}
+	}
+	if id := extractBeatportReleaseID("https://www.beatport.com/track/harbour-lights/17002"); id != 0 {
+		t.Errorf("track URL gave release ID %d", id)
+	}
+}
+
+func TestBeatportPageDataLookupIsStable(t *testing.T) {
+	// The same track and release appear in two places with different
+	// details; the lookup must settle on the same one every time.
+	page := `<script id="__NEXT_DATA__" type="application/json">{"props": {"pageProps": {
+		"track": {"id": 17002, "release": {"id": 4401, "slug": "harbour-lights"}},
+		"related": {"id": 17002, "release": {"id": 4401, "slug": "harbour-lights-remastered"}},
+		"release": {"id": 4401, "catalog_number": "COAST020", "label": {"name": "Coastline Recordings"}},
+		"alsoRelease": {"id": 4401, "catalog_number": "COAST020R", "label": {"name": "Coastline"}}
+	}}}</script>`
+	for i := 0; i < 20; i++ {
+		doc, err := goquery.NewDocumentFromReader(strings.NewReader(page))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := findBeatportTrackRelease(doc, 17002); got != "https://www.beatport.com/release/harbour-lights-remastered/4401" {
+			t.Fatalf("run %d: release = %q", i, got)
+		}
+		if label, catno := parseBeatportReleaseInfo(doc, 4401); label != "Coastline" || catno != "COAST020R" {
+			t.Fatalf("run %d: label %q, catalog number %q", i, label, catno)
+		}
+	}
+}
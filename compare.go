@@ -0,0 +1,336 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/adrg/strutil"
+	"github.com/adrg/strutil/metrics"
+)
+
+// The same release on two stores is spelled differently: Beatport adds
+// "(Original Mix)", Bandcamp often prefixes the artist. CompareSources lines
+// the track lists up so the user can take each field from the store that
+// spells it best, and MatchMergedSources names the files from that choice.
+
+// SourceComparison is several releases of the same music aligned track by
+// track.
+type SourceComparison struct {
+	URLs    []string       `json:"urls"`
+	Sources []AlbumData    `json:"sources"`
+	Rows    []AlignedTrack `json:"rows"`
+}
+
+// AlignedTrack is one track as it appears in each source, and the local file
+// it pairs with.
+type AlignedTrack struct {
+	// Tracks holds the index into each source's track list, or -1 when the
+	// source does not have the track.
+	Tracks       []int  `json:"tracks"`
+	LocalPath    string `json:"localPath"`
+	OriginalName string `json:"originalName"`
+}
+
+// SourceChoices names, for each field, the index of the source to take it
+// from. A track missing from the chosen source keeps the field from the
+// first source that has it.
+type SourceChoices struct {
+	// Title and Artist are per track.
+	Title  int `json:"title"`
+	Artist int `json:"artist"`
+	// Numbering supplies the track order, track and disc numbers and
+	// positions.
+	Numbering int `json:"numbering"`
+	// Album supplies the album title and album artist.
+	Album int `json:"album"`
+	// Label supplies the label and catalog number.
+	Label int `json:"label"`
+	// Year supplies the year and release date.
+	Year int `json:"year"`
+}
+
+// alignMinScore is the lowest similarity at which two stores' tracks are
+// taken to be the same track.
+const alignMinScore = 0.5
+
+// reOriginalMix matches the mix name stores add to the default version.
+var reOriginalMix = regexp.MustCompile(`(?i)\s*(?:\(\s*original(?: mix)?\s*\)|-\s*original mix)\s*$`)
+
+// CompareSources fetches every URL and aligns the releases' tracks to the
+// first one's, and the local files to the result.
+func (a *App) CompareSources(urls []string, localTracks []LocalTrack) (*SourceComparison, error) {
+	var cleaned []string
+	for _, url := range urls {
+		if url = strings.TrimSpace(url); url != "" {
+			cleaned = append(cleaned, url)
+		}
+	}
+	if len(cleaned) < 2 {
+		return nil, fmt.Errorf("enter at least two release URLs to compare")
+	}
+
+	ctx, done := a.startJob("compare")
+	defer done()
+	albums := make([]*AlbumData, len(cleaned))
+	errs := make([]error, len(cleaned))
+	var wg sync.WaitGroup
+	for i, url := range cleaned {
+		wg.Add(1)
+		go func(i int, url string) {
+			defer wg.Done()
+			albums[i], errs[i] = a.fetchAlbumData(ctx, url)
+		}(i, url)
+	}
+	wg.Wait()
+	for i, err := range errs {
+		if err != nil {
+			return nil, cancelledErr(ctx, fmt.Errorf("failed to fetch %s: %w", cleaned[i], err))
+		}
+	}
+
+	cmp := &SourceComparison{URLs: cleaned}
+	for _, album := range albums {
+		cmp.Sources = append(cmp.Sources, *album)
+	}
+	cmp.Rows = alignSources(cmp.Sources)
+
+	// Pair the local files using the first source's spelling.
+	merged, rowOf := mergeSources(cmp, SourceChoices{})
+	scores, _ := scoreAlbum(merged, localTracks)
+	for i, j := range solveAssignment(scores, 0) {
+		if j >= 0 {
+			cmp.Rows[rowOf[i]].LocalPath = localTracks[j].Path
+			cmp.Rows[rowOf[i]].OriginalName = localTracks[j].OriginalName
+		}
+	}
+	return cmp, nil
+}
+
+// MatchMergedSources builds one release from the compared sources, taking
+// each field from the chosen source, and matches the local files against it.
+func (a *App) MatchMergedSources(cmp SourceComparison, choices SourceChoices, localTracks []LocalTrack, pattern string) (*MatchResult, error) {
+	tmpl, err := resolveRenameTemplate(pattern)
+	if err != nil {
+		return nil, err
+	}
+	if len(cmp.Sources) == 0 {
+		return nil, fmt.Errorf("nothing to merge")
+	}
+	for _, choice := range []int{choices.Title, choices.Artist, choices.Numbering, choices.Album, choices.Label, choices.Year} {
+		if choice < 0 || choice >= len(cmp.Sources) {
+			return nil, fmt.Errorf("source %d does not exist", choice)
+		}
+	}
+	if err := cmp.checkRows(); err != nil {
+		return nil, err
+	}
+	merged, _ := mergeSources(&cmp, choices)
+	return matchAlbum(merged, cmp.URLs[choices.Album], cmp.Sources[choices.Numbering].Source, localTracks, tmpl), nil
+}
+
+// checkRows verifies that a comparison sent back by the frontend still fits
+// its sources: one URL per source, one index per source in every row, and
+// each index within that source's tracks.
+func (cmp *SourceComparison) checkRows() error {
+	if len(cmp.URLs) != len(cmp.Sources) {
+		return fmt.Errorf("comparison has %d URLs for %d sources", len(cmp.URLs), len(cmp.Sources))
+	}
+	for r, row := range cmp.Rows {
+		if len(row.Tracks) != len(cmp.Sources) {
+			return fmt.Errorf("track %d has %d sources, want %d", r+1, len(row.Tracks), len(cmp.Sources))
+		}
+		for s, idx := range row.Tracks {
+			if idx < -1 || idx >= len(cmp.Sources[s].Tracks) {
+				return fmt.Errorf("track %d refers to track %d of %s, which has %d", r+1, idx+1, cmp.Sources[s].Source, len(cmp.Sources[s].Tracks))
+			}
+		}
+	}
+	return nil
+}
+
+// alignSources lines up every source's tracks with the rows built so far,
+// starting from the first source. Tracks with no counterpart get rows of
+// their own.
+func alignSources(sources []AlbumData) []AlignedTrack {
+	var rows []AlignedTrack
+	newRow := func() AlignedTrack {
+		row := AlignedTrack{Tracks: make([]int, len(sources))}
+		for s := range row.Tracks {
+			row.Tracks[s] = -1
+		}
+		return row
+	}
+
+	sm := metrics.NewSorensenDice()
+	sm.CaseSensitive = false
+	for s, source := range sources {
+		scores := make([][]float64, len(source.Tracks))
+		for i, track := range source.Tracks {
+			scores[i] = make([]float64, len(rows))
+			for r, row := range rows {
+				scores[i][r] = alignScore(track, sources, row, sm)
+			}
+		}
+		assignment := solveAssignment(scores, alignMinScore)
+		for i, r := range assignment {
+			if r < 0 {
+				row := newRow()
+				row.Tracks[s] = i
+				rows = append(rows, row)
+				continue
+			}
+			rows[r].Tracks[s] = i
+		}
+	}
+	log.Printf("Aligned %d sources into %d tracks", len(sources), len(rows))
+	return rows
+}
+
+// alignScore rates how likely track is the same as the one in row, against
+// the first source that has it: title similarity, nudged by matching
+// durations and track numbers.
+func alignScore(track AlbumTrack, sources []AlbumData, row AlignedTrack, sm *metrics.SorensenDice) float64 {
+	for s, idx := range row.Tracks {
+		if idx < 0 {
+			continue
+		}
+		other := sources[s].Tracks[idx]
+		score := strutil.Similarity(comparableTitle(track), comparableTitle(other), sm)
+		if track.Duration > 0 && other.Duration > 0 {
+			if d := track.Duration - other.Duration; d > -durationTolerance && d < durationTolerance {
+				score += 0.1
+			} else {
+				score -= 0.1
+			}
+		}
+		if track.TrackNum > 0 && track.TrackNum == other.TrackNum && track.DiscNum == other.DiscNum {
+			score += 0.05
+		}
+		return max(0, min(1, score))
+	}
+	return 0
+}
+
+// comparableTitle reduces a store title to what stays the same across
+// stores: no "Artist - " prefix and no "(Original Mix)".
+func comparableTitle(t AlbumTrack) string {
+	t.Title = reOriginalMix.ReplaceAllString(t.Title, "")
+	_, title := splitStoreTitle(t)
+	return normalizeForMatch(title)
+}
+
+// splitStoreTitle separates the artist some stores put in the track title
+// ("Artist - Title"), the same way matching reads such titles.
+func splitStoreTitle(t AlbumTrack) (string, string) {
+	title := strings.TrimSpace(t.Title)
+	artist := strings.TrimSpace(t.Artist)
+	if artist != "" {
+		if prefix := artist + " - "; len(title) > len(prefix) && strings.EqualFold(title[:len(prefix)], prefix) {
+			title = title[len(prefix):]
+		}
+		return artist, title
+	}
+	if parts := strings.SplitN(title, " - ", 2); len(parts) == 2 {
+		return strings.TrimSpace(parts[0]), strings.TrimSpace(parts[1])
+	}
+	return "", title
+}
+
+// mergeSources builds one release from the comparison according to choices.
+// Tracks follow the numbering source's order; tracks it lacks come after,
+// numbered on from its last track. It also returns the row of every merged
+// track.
+func mergeSources(cmp *SourceComparison, choices SourceChoices) (*AlbumData, []int) {
+	pick := func(row AlignedTrack, preferred int) (AlbumTrack, bool) {
+		if idx := row.Tracks[preferred]; idx >= 0 {
+			return cmp.Sources[preferred].Tracks[idx], true
+		}
+		for s, idx := range row.Tracks {
+			if idx >= 0 {
+				return cmp.Sources[s].Tracks[idx], false
+			}
+		}
+		return AlbumTrack{}, false
+	}
+
+	numbering := cmp.Sources[choices.Numbering]
+	albumSource := cmp.Sources[choices.Album]
+	merged := &AlbumData{
+		Artist:        albumSource.Artist,
+		Title:         albumSource.Title,
+		Label:         cmp.Sources[choices.Label].Label,
+		CatalogNumber: cmp.Sources[choices.Label].CatalogNumber,
+		Year:          cmp.Sources[choices.Year].Year,
+		ReleaseDate:   cmp.Sources[choices.Year].ReleaseDate,
+		DiscTotal:     numbering.DiscTotal,
+	}
+	var providers []string
+	for _, source := range cmp.Sources {
+		if !containsFold(providers, source.Source) {
+			providers = append(providers, source.Source)
+		}
+	}
+	merged.Source = strings.Join(providers, " + ")
+
+	// Rows the numbering source has, in its order, then the rest.
+	order := make([]int, 0, len(cmp.Rows))
+	byIndex := make([]int, len(numbering.Tracks))
+	for i := range byIndex {
+		byIndex[i] = -1
+	}
+	for r, row := range cmp.Rows {
+		if idx := row.Tracks[choices.Numbering]; idx >= 0 {
+			byIndex[idx] = r
+		}
+	}
+	for _, r := range byIndex {
+		if r >= 0 {
+			order = append(order, r)
+		}
+	}
+	for r, row := range cmp.Rows {
+		if row.Tracks[choices.Numbering] < 0 {
+			order = append(order, r)
+		}
+	}
+
+	lastNum := 0
+	for _, r := range order {
+		row := cmp.Rows[r]
+		track, fromNumbering := pick(row, choices.Numbering)
+		if fromNumbering {
+			lastNum = max(lastNum, track.TrackNum)
+		} else {
+			lastNum++
+			track.TrackNum = lastNum
+			track.TrackNumExplicit = false
+			track.DiscNum, track.Position = 0, ""
+		}
+		// Split "Artist - Title" titles so the artist choice is not overridden
+		// by whatever the title source put in front of the title.
+		artist, title := splitStoreTitle(track)
+		if t, _ := pick(row, choices.Title); t.Title != "" {
+			_, title = splitStoreTitle(t)
+		}
+		if t, _ := pick(row, choices.Artist); t.Title != "" {
+			if a, _ := splitStoreTitle(t); a != "" {
+				artist = a
+			}
+		}
+		track.Artist, track.Title = artist, title
+		merged.Tracks = append(merged.Tracks, track)
+	}
+	return merged, order
+}
+
+func containsFold(values []string, v string) bool {
+	for _, existing := range values {
+		if strings.EqualFold(existing, v) {
+			return true
+		}
+	}
+	return false
+}
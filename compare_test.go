@@ -0,0 +1,74 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestMatchMergedSourcesKeepsNumberingStoreRules(t *testing.T) {
+	bandcamp := AlbumData{Artist: "Marlow", Title: "Salt", Source: "Bandcamp", Tracks: []AlbumTrack{
+		{Title: "Salt", TrackNum: 1, TrackNumExplicit: true},
+	}}
+	beatport := AlbumData{Artist: "Marlow", Title: "Salt", Source: "Beatport", Tracks: []AlbumTrack{
+		{Title: "Salt (Original Mix)", Artist: "Marlow", TrackNum: 5, TrackNumExplicit: true},
+	}}
+	cmp := SourceComparison{
+		URLs:    []string{"https://marlow.bandcamp.com/album/salt", "https://www.beatport.com/release/salt/1"},
+		Sources: []AlbumData{bandcamp, beatport},
+	}
+	cmp.Rows = alignSources(cmp.Sources)
+	local := []LocalTrack{{Path: "/in/02 Marlow - Salt.flac", OriginalName: "02 Marlow - Salt.flac"}}
+
+	// Beatport numbers tracks across its catalogue, so with Beatport
+	// numbering the local track number wins.
+	result, err := (&App{}).MatchMergedSources(cmp, SourceChoices{Numbering: 1}, local, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if name := result.Tracks[0].ProposedNewName; !strings.HasPrefix(name, "02. ") {
+		t.Errorf("Beatport numbering: name = %q, want track 02", name)
+	}
+
+	result, err = (&App{}).MatchMergedSources(cmp, SourceChoices{}, local, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if name := result.Tracks[0].ProposedNewName; !strings.HasPrefix(name, "01. ") {
+		t.Errorf("Bandcamp numbering: name = %q, want track 01", name)
+	}
+}
+
+func TestAlignSources(t *testing.T) {
+	sources := []AlbumData{
+		{Tracks: []AlbumTrack{{Title: "Marlow - Harbour Lights"}, {Title: "Salt"}, {Title: "Bonus Beats"}}},
+		{Tracks: []AlbumTrack{{Title: "Salt (Original Mix)", Artist: "Marlow"}, {Title: "Harbour Lights - Original Mix", Artist: "Marlow"}, {Title: "Currents", Artist: "Marlow"}}},
+	}
+	rows := alignSources(sources)
+	want := [][]int{{0, 1}, {1, 0}, {2, -1}, {-1, 2}}
+	if len(rows) != len(want) {
+		t.Fatalf("got %d rows, want %d: %+v", len(rows), len(want), rows)
+	}
+	for i, row := range rows {
+		if row.Tracks[0] != want[i][0] || row.Tracks[1] != want[i][1] {
+			t.Errorf("row %d = %v, want %v", i, row.Tracks, want[i])
+		}
+	}
+}
+
+func TestMatchMergedSourcesRejectsStaleRows(t *testing.T) {
+	sources := []AlbumData{
+		{Source: "Bandcamp", Tracks: []AlbumTrack{{Title: "Salt", TrackNum: 1}}},
+		{Source: "Beatport", Tracks: []AlbumTrack{{Title: "Salt", TrackNum: 1}}},
+	}
+	urls := []string{"https://marlow.bandcamp.com/album/salt", "https://www.beatport.com/release/salt/1"}
+	for name, cmp := range map[string]SourceComparison{
+		"index past the end": {URLs: urls, Sources: sources, Rows: []AlignedTrack{{Tracks: []int{0, 3}}}},
+		"negative index":     {URLs: urls, Sources: sources, Rows: []AlignedTrack{{Tracks: []int{-2, 0}}}},
+		"missing source":     {URLs: urls, Sources: sources, Rows: []AlignedTrack{{Tracks: []int{0}}}},
+		"missing URL":        {URLs: urls[:1], Sources: sources, Rows: []AlignedTrack{{Tracks: []int{0, 0}}}},
+	} {
+		if _, err := (&App{}).MatchMergedSources(cmp, SourceChoices{Numbering: 1}, nil, ""); err == nil {
+			t.Errorf("%s: accepted", name)
+		}
+	}
+}
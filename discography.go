@@ -0,0 +1,124 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+)
+
+// Artist and label pages list many releases. The frontend shows the list so
+// the user can pick one, or asks PickRelease for the release whose tracks
+// overlap most with the selected folder.
+
+const (
+	// maxPickCandidates caps how many releases PickRelease downloads.
+	maxPickCandidates = 40
+	// pickConcurrency is how many release pages are fetched at once.
+	pickConcurrency = 4
+	// pickMinScore is the lowest pair score counted as an overlapping track.
+	pickMinScore = 0.5
+)
+
+// ListReleases returns the releases on an artist or label page. For a release
+// URL it returns nothing, and the URL can be matched directly.
+func (a *App) ListReleases(url string) ([]ReleaseSummary, error) {
+	url = strings.TrimSpace(url)
+	provider, err := providerForURL(url)
+	if err != nil {
+		return nil, err
+	}
+	discography, ok := provider.(DiscographyProvider)
+	if !ok || !discography.IsDiscography(url) {
+		return []ReleaseSummary{}, nil
+	}
+	ctx, done := a.startJob("releases")
+	defer done()
+	releases, err := discography.Discography(ctx, url)
+	return releases, cancelledErr(ctx, err)
+}
+
+// PickRelease fetches the candidate releases and returns the one whose tracks
+// overlap most with localTracks.
+func (a *App) PickRelease(releases []ReleaseSummary, localTracks []LocalTrack) (*ReleaseSummary, error) {
+	if len(releases) == 0 {
+		return nil, fmt.Errorf("no releases to choose from")
+	}
+	if len(localTracks) == 0 {
+		return nil, fmt.Errorf("no local tracks to compare with")
+	}
+	candidates := releases
+	if len(candidates) > maxPickCandidates {
+		log.Printf("Only comparing the first %d of %d releases", maxPickCandidates, len(candidates))
+		candidates = candidates[:maxPickCandidates]
+	}
+
+	ctx, done := a.startJob("pick")
+	defer done()
+	overlaps := a.compareReleases(ctx, candidates, localTracks)
+	if err := cancelledErr(ctx, ctx.Err()); err != nil {
+		return nil, err
+	}
+
+	best := -1
+	for i, overlap := range overlaps {
+		if overlap > 0 && (best < 0 || overlap > overlaps[best]) {
+			best = i
+		}
+	}
+	if best < 0 {
+		return nil, fmt.Errorf("none of the %d releases has tracks matching the selected files", len(candidates))
+	}
+	log.Printf("Picked %s (overlap %.2f)", candidates[best].URL, overlaps[best])
+	picked := candidates[best]
+	return &picked, nil
+}
+
+// compareReleases fetches each release and rates its overlap with
+// localTracks (see trackOverlap), a few at a time. Releases that cannot be
+// fetched get -1. The track count of each fetched release is filled in.
+func (a *App) compareReleases(ctx context.Context, releases []ReleaseSummary, localTracks []LocalTrack) []float64 {
+	overlaps := make([]float64, len(releases))
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, pickConcurrency)
+	for i := range releases {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+			if ctx.Err() != nil {
+				overlaps[i] = -1
+				return
+			}
+			album, err := a.fetchAlbumData(ctx, releases[i].URL)
+			if err != nil {
+				log.Printf("Skipping %s: %v", releases[i].URL, err)
+				overlaps[i] = -1
+				return
+			}
+			releases[i].TrackCount = len(album.Tracks)
+			overlaps[i] = trackOverlap(album, localTracks)
+		}(i)
+	}
+	wg.Wait()
+	return overlaps
+}
+
+// trackOverlap rates how well album covers localTracks, from 0 to 1: the
+// summed scores of the best pairing, divided by the larger of the two track
+// counts so a release much longer or shorter than the folder ranks lower.
+func trackOverlap(album *AlbumData, localTracks []LocalTrack) float64 {
+	if len(album.Tracks) == 0 || len(localTracks) == 0 {
+		return 0
+	}
+	scores, _ := scoreAlbum(album, localTracks)
+	total := 0.0
+	for i, j := range solveAssignment(scores, pickMinScore) {
+		if j >= 0 {
+			total += scores[i][j]
+		}
+	}
+	return total / float64(max(len(album.Tracks), len(localTracks)))
+}
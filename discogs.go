@@ -0,0 +1,245 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	neturl "net/url"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Discogs is used through its public API; release pages themselves sit
+// behind bot protection. Release lookups work anonymously, search needs a
+// personal access token in DISCOGS_TOKEN.
+
+var (
+	reDiscogsRelease = regexp.MustCompile(`(?i)/releases?/(\d+)`)
+	reDiscogsMaster  = regexp.MustCompile(`(?i)/masters?/(\d+)`)
+	// Multi-disc CD positions: "1-01", "2.3", "CD2-05".
+	reDiscogsDiscPosition = regexp.MustCompile(`(?i)^(?:CD|DVD|Disc)?\s*(\d+)[-.](\d+)$`)
+	// Discogs disambiguates artists with the same name as "Name (2)".
+	reDiscogsArtistSuffix = regexp.MustCompile(`\s+\(\d+\)$`)
+)
+
+type discogsProvider struct {
+	Token string
+}
+
+type discogsArtist struct {
+	Name string `json:"name"`
+	ANV  string `json:"anv"`
+	Join string `json:"join"`
+	Role string `json:"role"`
+}
+
+type discogsTrack struct {
+	Position     string          `json:"position"`
+	Type         string          `json:"type_"`
+	Title        string          `json:"title"`
+	Duration     string          `json:"duration"`
+	Artists      []discogsArtist `json:"artists"`
+	ExtraArtists []discogsArtist `json:"extraartists"`
+}
+
+type discogsRelease struct {
+	Title   string          `json:"title"`
+	Artists []discogsArtist `json:"artists"`
+	Labels  []struct {
+		Name  string `json:"name"`
+		CatNo string `json:"catno"`
+	} `json:"labels"`
+	Year      int            `json:"year"`
+	Tracklist []discogsTrack `json:"tracklist"`
+}
+
+func newDiscogsProvider() discogsProvider {
+	return discogsProvider{Token: strings.TrimSpace(os.Getenv("DISCOGS_TOKEN"))}
+}
+
+func (discogsProvider) Name() string { return "Discogs" }
+
+func (discogsProvider) CanHandle(url string) bool {
+	return hostIs(urlHost(url), "discogs.com") &&
+		(reDiscogsRelease.MatchString(url) || reDiscogsMaster.MatchString(url))
+}
+
+func (p discogsProvider) Fetch(ctx context.Context, url string) (*AlbumData, error) {
+	var release discogsRelease
+	if m := reDiscogsRelease.FindStringSubmatch(url); m != nil {
+		if err := p.getJSON(ctx, "https://api.discogs.com/releases/"+m[1], &release); err != nil {
+			return nil, err
+		}
+	} else if m := reDiscogsMaster.FindStringSubmatch(url); m != nil {
+		// A master groups all pressings; use its main release.
+		var master struct {
+			MainRelease int `json:"main_release"`
+		}
+		if err := p.getJSON(ctx, "https://api.discogs.com/masters/"+m[1], &master); err != nil {
+			return nil, err
+		}
+		if master.MainRelease == 0 {
+			return nil, fmt.Errorf("Discogs master %s has no main release", m[1])
+		}
+		if err := p.getJSON(ctx, "https://api.discogs.com/releases/"+strconv.Itoa(master.MainRelease), &release); err != nil {
+			return nil, err
+		}
+	} else {
+		return nil, fmt.Errorf("could not find a Discogs release ID in %s", url)
+	}
+	return p.parse(&release)
+}
+
+func (p discogsProvider) getJSON(ctx context.Context, url string, out interface{}) error {
+	header := http.Header{}
+	if p.Token != "" {
+		header.Set("Authorization", "Discogs token="+p.Token)
+	}
+	res, err := getWithHeaders(ctx, url, header)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	if res.StatusCode != 200 {
+		return fmt.Errorf("status code error: %d %s", res.StatusCode, res.Status)
+	}
+	data, err := io.ReadAll(res.Body)
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(data, out); err != nil {
+		return fmt.Errorf("failed to unmarshal Discogs response: %w", err)
+	}
+	return nil
+}
+
+// parse turns an API release into AlbumData. Headings are skipped, an index
+// track (a medley with sub-tracks) counts as one track, and tracks are
+// numbered sequentially so A1, A2, B1 become 1, 2, 3. Multi-disc positions
+// such as 2-05 keep their disc and track numbers.
+func (p discogsProvider) parse(release *discogsRelease) (*AlbumData, error) {
+	album := &AlbumData{
+		Artist: discogsArtistCredit(release.Artists),
+		Title:  strings.TrimSpace(release.Title),
+		Year:   release.Year,
+		Source: p.Name(),
+	}
+	if len(release.Labels) > 0 {
+		album.Label = reDiscogsArtistSuffix.ReplaceAllString(strings.TrimSpace(release.Labels[0].Name), "")
+		if catno := strings.TrimSpace(release.Labels[0].CatNo); !strings.EqualFold(catno, "none") {
+			album.CatalogNumber = catno
+		}
+	}
+	for _, t := range release.Tracklist {
+		if t.Type != "" && t.Type != "track" && t.Type != "index" {
+			continue
+		}
+		title := strings.TrimSpace(t.Title)
+		if title == "" {
+			continue
+		}
+		album.Tracks = append(album.Tracks, AlbumTrack{
+			Title:            title,
+			Artist:           discogsArtistCredit(t.Artists),
+			TrackNum:         len(album.Tracks) + 1,
+			TrackNumExplicit: true,
+			Duration:         parseDurationString(t.Duration),
+			Position:         strings.TrimSpace(t.Position),
+			Credits:          discogsCredits(t.ExtraArtists),
+		})
+	}
+	if len(album.Tracks) == 0 {
+		return nil, fmt.Errorf("Discogs release has no tracks")
+	}
+
+	for _, t := range album.Tracks {
+		if !reDiscogsDiscPosition.MatchString(t.Position) {
+			return album, nil
+		}
+	}
+	for i := range album.Tracks {
+		m := reDiscogsDiscPosition.FindStringSubmatch(album.Tracks[i].Position)
+		album.Tracks[i].DiscNum, _ = strconv.Atoi(m[1])
+		album.Tracks[i].TrackNum, _ = strconv.Atoi(m[2])
+		album.Tracks[i].Position = ""
+		album.DiscTotal = max(album.DiscTotal, album.Tracks[i].DiscNum)
+	}
+	return album, nil
+}
+
+// discogsArtistCredit joins artists the way Discogs displays them, using the
+// name variation when one was credited.
+func discogsArtistCredit(artists []discogsArtist) string {
+	var b strings.Builder
+	for i, a := range artists {
+		name := strings.TrimSpace(a.ANV)
+		if name == "" {
+			name = reDiscogsArtistSuffix.ReplaceAllString(strings.TrimSpace(a.Name), "")
+		}
+		b.WriteString(name)
+		if i < len(artists)-1 {
+			join := strings.TrimSpace(a.Join)
+			switch join {
+			case "", ",":
+				b.WriteString(join + " ")
+			default:
+				b.WriteString(" " + join + " ")
+			}
+		}
+	}
+	return strings.TrimSpace(b.String())
+}
+
+func discogsCredits(extra []discogsArtist) string {
+	credits := make([]string, 0, len(extra))
+	for _, a := range extra {
+		name := discogsArtistCredit([]discogsArtist{a})
+		if role := strings.TrimSpace(a.Role); role != "" {
+			name = role + " – " + name
+		}
+		credits = append(credits, name)
+	}
+	return strings.Join(credits, "; ")
+}
+
+func (p discogsProvider) Search(ctx context.Context, query string) ([]ReleaseSummary, error) {
+	if p.Token == "" {
+		return nil, fmt.Errorf("Discogs search needs a personal access token in DISCOGS_TOKEN")
+	}
+	var page struct {
+		Results []struct {
+			ID    int      `json:"id"`
+			Title string   `json:"title"`
+			URI   string   `json:"uri"`
+			Label []string `json:"label"`
+			CatNo string   `json:"catno"`
+		} `json:"results"`
+	}
+	url := "https://api.discogs.com/database/search?type=release&q=" + neturl.QueryEscape(query)
+	if err := p.getJSON(ctx, url, &page); err != nil {
+		return nil, err
+	}
+	results := make([]ReleaseSummary, 0, len(page.Results))
+	for _, r := range page.Results {
+		// Search titles are "Artist - Title".
+		artist, title := "", r.Title
+		if parts := strings.SplitN(r.Title, " - ", 2); len(parts) == 2 {
+			artist, title = parts[0], parts[1]
+		}
+		summary := ReleaseSummary{
+			Provider: p.Name(),
+			URL:      "https://www.discogs.com/release/" + strconv.Itoa(r.ID),
+			Artist:   reDiscogsArtistSuffix.ReplaceAllString(strings.TrimSpace(artist), ""),
+			Title:    strings.TrimSpace(title),
+		}
+		if len(r.Label) > 0 {
+			summary.Label = r.Label[0]
+		}
+		results = append(results, summary)
+	}
+	return results, nil
+}
@@ -0,0 +1,72 @@
+package main
+
+import "testing"
+
+func TestDiscogsParseMultiDisc(t *testing.T) {
+	var release discogsRelease
+	loadJSONFixture(t, "discogs/release-2cd.json", &release)
+	album, err := discogsProvider{}.parse(&release)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if album.Artist != "Marlow" || album.Label != "Coastline Recordings" || album.CatalogNumber != "COAST CD 20" {
+		t.Errorf("album = %q, label %q %q", album.Artist, album.Label, album.CatalogNumber)
+	}
+	if album.DiscTotal != 2 {
+		t.Errorf("DiscTotal = %d, want 2", album.DiscTotal)
+	}
+	// Headings are dropped and the index track counts once; "1-1", "CD2-01"
+	// and "2.2" all give disc and track numbers.
+	checkTracks(t, album, []AlbumTrack{
+		{Title: "Harbour Lights", Artist: "Marlow Feat. I. Vale", TrackNum: 1, DiscNum: 1, Duration: 372},
+		{Title: "Salt", TrackNum: 2, DiscNum: 1, Duration: 340},
+		{Title: "Currents", TrackNum: 1, DiscNum: 2, Duration: 423},
+		{Title: "Tidal Suite", TrackNum: 2, DiscNum: 2, Duration: 750},
+	})
+	if got := album.Tracks[0].Credits; got != "Remix – Sam Ortega" {
+		t.Errorf("credits = %q", got)
+	}
+}
+
+func TestDiscogsParseVinyl(t *testing.T) {
+	var release discogsRelease
+	loadJSONFixture(t, "discogs/release-vinyl.json", &release)
+	album, err := discogsProvider{}.parse(&release)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if album.CatalogNumber != "" || album.DiscTotal != 0 {
+		t.Errorf("catno %q, discs %d; want none and no disc structure", album.CatalogNumber, album.DiscTotal)
+	}
+	// Sides are numbered straight through; the untitled track is skipped.
+	checkTracks(t, album, []AlbumTrack{
+		{Title: "Sonar", Artist: "Deepline, Hull", TrackNum: 1, Position: "A1", Duration: 465},
+		{Title: "Ballast", TrackNum: 2, Position: "A2"},
+		{Title: "Keel", TrackNum: 3, Position: "B1", Duration: 481},
+	})
+}
+
+func TestDiscogsDiscPosition(t *testing.T) {
+	for pos, want := range map[string][2]string{
+		"1-01":    {"1", "01"},
+		"2.3":     {"2", "3"},
+		"CD2-05":  {"2", "05"},
+		"cd 3-1":  {"3", "1"},
+		"Disc1.4": {"1", "4"},
+		"A1":      {},
+		"12":      {},
+		"1-":      {},
+		"2.2a":    {},
+	} {
+		m := reDiscogsDiscPosition.FindStringSubmatch(pos)
+		var got [2]string
+		if m != nil {
+			got = [2]string{m[1], m[2]}
+		}
+		if got != want {
+			t.Errorf("%q: got %q, want %q", pos, got, want)
+		}
+	}
+}
@@ -0,0 +1,313 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Durations are read straight from the container headers, without decoding
+// audio. They are in seconds; 0 means unknown.
+
+// readAudioDuration returns the playing time of path, using the reader
+// registered for its format.
+func readAudioDuration(path string, format *AudioFormat) float64 {
+	if format == nil || format.Duration == nil {
+		return 0
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return 0
+	}
+	defer f.Close()
+	d, err := format.Duration(f)
+	if err != nil || d < 0 || math.IsInf(d, 0) || math.IsNaN(d) {
+		return 0
+	}
+	return d
+}
+
+// skipID3v2 positions r after a leading ID3v2 tag, if there is one, and
+// returns the offset the audio starts at.
+func skipID3v2(r io.ReadSeeker) (int64, error) {
+	head := make([]byte, 10)
+	if _, err := io.ReadFull(r, head); err != nil {
+		return 0, err
+	}
+	if string(head[0:3]) != "ID3" {
+		_, err := r.Seek(0, io.SeekStart)
+		return 0, err
+	}
+	offset := id3v2End(head)
+	_, err := r.Seek(offset, io.SeekStart)
+	return offset, err
+}
+
+// flacDuration reads the total sample count and sample rate from STREAMINFO,
+// which is always the first metadata block.
+func flacDuration(r io.ReadSeeker) (float64, error) {
+	if _, err := skipID3v2(r); err != nil {
+		return 0, err
+	}
+	buf := make([]byte, 4+4+34)
+	if _, err := io.ReadFull(r, buf); err != nil {
+		return 0, err
+	}
+	if string(buf[0:4]) != "fLaC" || buf[4]&0x7F != 0 {
+		return 0, fmt.Errorf("no STREAMINFO block")
+	}
+	info := buf[8:]
+	sampleRate := uint64(info[10])<<12 | uint64(info[11])<<4 | uint64(info[12])>>4
+	totalSamples := uint64(info[13]&0x0F)<<32 | uint64(binary.BigEndian.Uint32(info[14:18]))
+	if sampleRate == 0 {
+		return 0, fmt.Errorf("invalid sample rate")
+	}
+	return float64(totalSamples) / float64(sampleRate), nil
+}
+
+// wavDuration divides the size of the data chunk by the byte rate from the
+// fmt chunk. RF64 files keep the real data size in the ds64 chunk.
+func wavDuration(r io.ReadSeeker) (float64, error) {
+	head := make([]byte, 12)
+	if _, err := io.ReadFull(r, head); err != nil {
+		return 0, err
+	}
+	rf64 := string(head[0:4]) == "RF64"
+	var byteRate, dataSize uint64
+	chunk := make([]byte, 8)
+	for {
+		if _, err := io.ReadFull(r, chunk); err != nil {
+			break
+		}
+		id := string(chunk[0:4])
+		size := int64(binary.LittleEndian.Uint32(chunk[4:8]))
+		switch id {
+		case "fmt ", "ds64":
+			body := make([]byte, min(size, 64))
+			if _, err := io.ReadFull(r, body); err != nil {
+				return 0, err
+			}
+			if id == "fmt " && len(body) >= 12 {
+				byteRate = uint64(binary.LittleEndian.Uint32(body[8:12]))
+			} else if id == "ds64" && len(body) >= 16 {
+				dataSize = binary.LittleEndian.Uint64(body[8:16])
+			}
+			size -= int64(len(body))
+		case "data":
+			if !rf64 || dataSize == 0 {
+				dataSize = uint64(size)
+			}
+			if byteRate == 0 {
+				return 0, fmt.Errorf("data chunk before fmt chunk")
+			}
+			return float64(dataSize) / float64(byteRate), nil
+		}
+		// Chunks are padded to an even size.
+		if _, err := r.Seek(size+size%2, io.SeekCurrent); err != nil {
+			return 0, err
+		}
+	}
+	return 0, fmt.Errorf("no data chunk")
+}
+
+// aiffDuration reads the frame count and sample rate from the COMM chunk.
+func aiffDuration(r io.ReadSeeker) (float64, error) {
+	head := make([]byte, 12)
+	if _, err := io.ReadFull(r, head); err != nil {
+		return 0, err
+	}
+	chunk := make([]byte, 8)
+	for {
+		if _, err := io.ReadFull(r, chunk); err != nil {
+			return 0, fmt.Errorf("no COMM chunk")
+		}
+		size := int64(binary.BigEndian.Uint32(chunk[4:8]))
+		if string(chunk[0:4]) == "COMM" {
+			body := make([]byte, 18)
+			if _, err := io.ReadFull(r, body); err != nil {
+				return 0, err
+			}
+			frames := binary.BigEndian.Uint32(body[2:6])
+			rate := extendedToFloat(body[8:18])
+			if rate <= 0 {
+				return 0, fmt.Errorf("invalid sample rate")
+			}
+			return float64(frames) / rate, nil
+		}
+		if _, err := r.Seek(size+size%2, io.SeekCurrent); err != nil {
+			return 0, err
+		}
+	}
+}
+
+// extendedToFloat decodes the 80-bit IEEE 754 extended float AIFF uses for
+// its sample rate.
+func extendedToFloat(b []byte) float64 {
+	exp := int(binary.BigEndian.Uint16(b[0:2]) & 0x7FFF)
+	mantissa := binary.BigEndian.Uint64(b[2:10])
+	if exp == 0 && mantissa == 0 {
+		return 0
+	}
+	v := math.Ldexp(float64(mantissa), exp-16383-63)
+	if b[0]&0x80 != 0 {
+		v = -v
+	}
+	return v
+}
+
+var (
+	mpegSampleRates = map[int][3]int{
+		3: {44100, 48000, 32000}, // MPEG-1
+		2: {22050, 24000, 16000}, // MPEG-2
+		0: {11025, 12000, 8000},  // MPEG-2.5
+	}
+	// Bitrates in kbit/s by [MPEG-1?][layer], indexed by the header's index.
+	mpegBitrates = map[bool]map[int][16]int{
+		true: {
+			1: {0, 32, 64, 96, 128, 160, 192, 224, 256, 288, 320, 352, 384, 416, 448},
+			2: {0, 32, 48, 56, 64, 80, 96, 112, 128, 160, 192, 224, 256, 320, 384},
+			3: {0, 32, 40, 48, 56, 64, 80, 96, 112, 128, 160, 192, 224, 256, 320},
+		},
+		false: {
+			1: {0, 32, 48, 56, 64, 80, 96, 112, 128, 144, 160, 176, 192, 224, 256},
+			2: {0, 8, 16, 24, 32, 40, 48, 56, 64, 80, 96, 112, 128, 144, 160},
+			3: {0, 8, 16, 24, 32, 40, 48, 56, 64, 80, 96, 112, 128, 144, 160},
+		},
+	}
+)
+
+// mp3Duration uses the frame count from a Xing/Info or VBRI header when the
+// encoder wrote one, and otherwise assumes a constant bitrate and divides
+// the audio size by the bitrate of the first frame.
+func mp3Duration(r io.ReadSeeker) (float64, error) {
+	start, err := skipID3v2(r)
+	if err != nil {
+		return 0, err
+	}
+	buf := make([]byte, 16*1024)
+	n, err := io.ReadFull(r, buf)
+	if err != nil && err != io.ErrUnexpectedEOF {
+		return 0, err
+	}
+	buf = buf[:n]
+
+	pos := -1
+	for i := 0; i+4 <= len(buf); i++ {
+		if isMPEGFrameSync(buf[i], buf[i+1]) && buf[i+2]>>4 != 0x0F && (buf[i+2]>>2)&0x03 != 0x03 {
+			pos = i
+			break
+		}
+	}
+	if pos < 0 {
+		return 0, fmt.Errorf("no MPEG frame found")
+	}
+	h := buf[pos:]
+	version := int(h[1]>>3) & 0x03
+	layer := 4 - int(h[1]>>1)&0x03
+	if version == 1 || layer == 4 {
+		return 0, fmt.Errorf("reserved MPEG version or layer")
+	}
+	mpeg1 := version == 3
+	sampleRate := mpegSampleRates[version][(h[2]>>2)&0x03]
+	bitrate := mpegBitrates[mpeg1][layer][h[2]>>4] * 1000
+	mono := h[3]>>6 == 0x03
+
+	samplesPerFrame := 1152
+	if layer == 1 {
+		samplesPerFrame = 384
+	} else if layer == 3 && !mpeg1 {
+		samplesPerFrame = 576
+	}
+
+	sideInfo := 32
+	switch {
+	case mpeg1 && mono:
+		sideInfo = 17
+	case !mpeg1 && mono:
+		sideInfo = 9
+	case !mpeg1:
+		sideInfo = 17
+	}
+	if x := 4 + sideInfo; len(h) >= x+12 {
+		tag := string(h[x : x+4])
+		if (tag == "Xing" || tag == "Info") && binary.BigEndian.Uint32(h[x+4:x+8])&0x01 != 0 {
+			frames := binary.BigEndian.Uint32(h[x+8 : x+12])
+			return float64(frames) * float64(samplesPerFrame) / float64(sampleRate), nil
+		}
+	}
+	if len(h) >= 36+18 && string(h[36:40]) == "VBRI" {
+		frames := binary.BigEndian.Uint32(h[36+14 : 36+18])
+		return float64(frames) * float64(samplesPerFrame) / float64(sampleRate), nil
+	}
+
+	if bitrate == 0 {
+		return 0, fmt.Errorf("free-format bitrate")
+	}
+	end, err := r.Seek(0, io.SeekEnd)
+	if err != nil {
+		return 0, err
+	}
+	if end >= 128 {
+		trailer := make([]byte, 3)
+		if _, err := r.Seek(end-128, io.SeekStart); err == nil {
+			if _, err := io.ReadFull(r, trailer); err == nil && bytes.Equal(trailer, []byte("TAG")) {
+				end -= 128
+			}
+		}
+	}
+	audioBytes := end - start - int64(pos)
+	return float64(audioBytes) * 8 / float64(bitrate), nil
+}
+
+var (
+	reISODuration   = regexp.MustCompile(`(?i)^P(?:T)?(?:(\d+(?:\.\d+)?)H)?(?:(\d+(?:\.\d+)?)M)?(?:(\d+(?:\.\d+)?)S)?$`)
+	reClockDuration = regexp.MustCompile(`^(?:(\d+):)?(\d{1,2}):(\d{2})(?:\.\d+)?$`)
+)
+
+// parseDurationString understands the ISO 8601 durations used in JSON-LD
+// ("PT6M12S") and clock times ("6:12", "1:02:03"). It returns seconds.
+func parseDurationString(s string) float64 {
+	s = strings.TrimSpace(s)
+	if m := reISODuration.FindStringSubmatch(s); m != nil && s != "P" && s != "PT" {
+		total := 0.0
+		for i, unit := range []float64{3600, 60, 1} {
+			if m[i+1] != "" {
+				v, _ := strconv.ParseFloat(m[i+1], 64)
+				total += v * unit
+			}
+		}
+		return total
+	}
+	if m := reClockDuration.FindStringSubmatch(s); m != nil {
+		h, _ := strconv.Atoi(m[1])
+		mins, _ := strconv.Atoi(m[2])
+		secs, _ := strconv.Atoi(m[3])
+		return float64(h*3600 + mins*60 + secs)
+	}
+	return 0
+}
+
+// extractDurationFromObj reads a track duration from a store's JSON object.
+// Beatport uses length_ms and a "6:12" length string; JSON-LD uses an ISO
+// 8601 duration.
+func extractDurationFromObj(obj map[string]interface{}) float64 {
+	for _, key := range []string{"length_ms", "lengthMs", "duration_ms", "durationMs"} {
+		if ms := parseIntFromAny(obj[key]); ms > 0 {
+			return float64(ms) / 1000
+		}
+	}
+	for _, key := range []string{"duration", "length"} {
+		if s, ok := obj[key].(string); ok {
+			if d := parseDurationString(s); d > 0 {
+				return d
+			}
+		}
+	}
+	return 0
+}
@@ -0,0 +1,139 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math"
+	"testing"
+)
+
+// id3Tag returns an ID3v2.4 tag with the given syncsafe size bytes and as
+// many padding bytes as they encode.
+func id3Tag(size [4]byte) []byte {
+	n := int(size[0])<<21 | int(size[1])<<14 | int(size[2])<<7 | int(size[3])
+	tag := append([]byte{'I', 'D', '3', 4, 0, 0}, size[:]...)
+	return append(tag, make([]byte, n)...)
+}
+
+func flacStream(sampleRate uint32, totalSamples uint64) []byte {
+	info := make([]byte, 34)
+	info[10] = byte(sampleRate >> 12)
+	info[11] = byte(sampleRate >> 4)
+	info[12] = byte(sampleRate<<4) | 0x02 // 2 channels - 1, 16 bits
+	info[13] = 0xF0 | byte(totalSamples>>32)
+	binary.BigEndian.PutUint32(info[14:18], uint32(totalSamples))
+	out := []byte("fLaC")
+	out = append(out, 0x80, 0, 0, 34) // last block, STREAMINFO
+	return append(out, info...)
+}
+
+func TestID3v2End(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		head []byte
+		want int64
+	}{
+		{"small", []byte{'I', 'D', '3', 4, 0, 0, 0, 0, 0, 0x0A}, 20},
+		{"low bits overlap the header", []byte{'I', 'D', '3', 4, 0, 0, 0, 0, 0x08, 0x0A}, 1044},
+		{"footer", []byte{'I', 'D', '3', 4, 0, 0x10, 0, 0, 0x01, 0}, 148},
+	} {
+		if got := id3v2End(tc.head); got != tc.want {
+			t.Errorf("%s: id3v2End = %d, want %d", tc.name, got, tc.want)
+		}
+	}
+}
+
+func TestFLACDuration(t *testing.T) {
+	plain := flacStream(44100, 44100*90)
+	tagged := append(id3Tag([4]byte{0, 0, 0x08, 0x0A}), plain...)
+	for name, data := range map[string][]byte{"plain": plain, "ID3 tagged": tagged} {
+		got, err := flacDuration(bytes.NewReader(data))
+		if err != nil {
+			t.Errorf("%s: %v", name, err)
+			continue
+		}
+		if got != 90 {
+			t.Errorf("%s: duration = %v, want 90", name, got)
+		}
+	}
+}
+
+func TestWAVDuration(t *testing.T) {
+	var b bytes.Buffer
+	b.WriteString("RIFF\x00\x00\x00\x00WAVE")
+	b.WriteString("LIST")
+	binary.Write(&b, binary.LittleEndian, uint32(3))
+	b.WriteString("abc\x00") // odd size, padded
+	b.WriteString("fmt ")
+	binary.Write(&b, binary.LittleEndian, uint32(16))
+	fmtChunk := make([]byte, 16)
+	binary.LittleEndian.PutUint32(fmtChunk[8:12], 176400)
+	b.Write(fmtChunk)
+	b.WriteString("data")
+	binary.Write(&b, binary.LittleEndian, uint32(176400*3))
+
+	got, err := wavDuration(bytes.NewReader(b.Bytes()))
+	if err != nil || got != 3 {
+		t.Errorf("wavDuration = %v, %v; want 3", got, err)
+	}
+}
+
+func TestAIFFDuration(t *testing.T) {
+	var b bytes.Buffer
+	b.WriteString("FORM\x00\x00\x00\x00AIFF")
+	b.WriteString("COMM")
+	binary.Write(&b, binary.BigEndian, uint32(18))
+	comm := make([]byte, 18)
+	binary.BigEndian.PutUint32(comm[2:6], 44100*5)
+	copy(comm[8:], []byte{0x40, 0x0E, 0xAC, 0x44, 0, 0, 0, 0, 0, 0}) // 44100
+	b.Write(comm)
+
+	got, err := aiffDuration(bytes.NewReader(b.Bytes()))
+	if err != nil || got != 5 {
+		t.Errorf("aiffDuration = %v, %v; want 5", got, err)
+	}
+}
+
+func TestMP3Duration(t *testing.T) {
+	// MPEG-1 layer III, 128 kbit/s, 44.1 kHz, stereo.
+	frameHeader := []byte{0xFF, 0xFB, 0x90, 0x00}
+
+	cbr := append(id3Tag([4]byte{0, 0, 0x08, 0x0A}), frameHeader...)
+	cbr = append(cbr, make([]byte, 16000-4)...)
+	got, err := mp3Duration(bytes.NewReader(cbr))
+	if err != nil || got != 1 {
+		t.Errorf("CBR: mp3Duration = %v, %v; want 1", got, err)
+	}
+
+	xing := append([]byte{}, frameHeader...)
+	xing = append(xing, make([]byte, 32)...)
+	xing = append(xing, 'X', 'i', 'n', 'g', 0, 0, 0, 1)
+	xing = binary.BigEndian.AppendUint32(xing, 3828) // frames
+	xing = append(xing, make([]byte, 400)...)
+	got, err = mp3Duration(bytes.NewReader(xing))
+	if err != nil || math.Abs(got-3828*1152/44100.0) > 1e-9 {
+		t.Errorf("Xing: mp3Duration = %v, %v; want %v", got, err, 3828*1152/44100.0)
+	}
+}
+
+func TestParseDurationString(t *testing.T) {
+	for in, want := range map[string]float64{
+		"PT6M12S":   372,
+		"P0DT1H2M":  0,
+		"PT1H2M3S":  3723,
+		"PT4.5S":    4.5,
+		"6:12":      372,
+		"(6:12)":    0,
+		"1:02:03":   3723,
+		"3:07.250":  187,
+		"":          0,
+		"PT":        0,
+		"six":       0,
+		"12:3":      0,
+		"  04:05  ": 245,
+	} {
+		if got := parseDurationString(in); got != want {
+			t.Errorf("parseDurationString(%q) = %v, want %v", in, got, want)
+		}
+	}
+}
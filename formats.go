@@ -0,0 +1,280 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/dhowden/tag"
+)
+
+// AudioFormat describes one supported container. Extensions are matched
+// case-insensitively; Sniff inspects the first bytes of the file (after any
+// leading ID3v2 tag) and reports whether they look like this format.
+// Duration, when set, reads the playing time in seconds from the headers.
+type AudioFormat struct {
+	Name       string
+	Extensions []string
+	Sniff      func(header []byte) bool
+	TagTypes   []tag.FileType
+	Duration   func(r io.ReadSeeker) (float64, error)
+}
+
+// SkippedFile is a file the scanner saw but did not treat as audio.
+type SkippedFile struct {
+	Path   string `json:"path"`
+	Name   string `json:"name"`
+	Reason string `json:"reason"`
+}
+
+const sniffHeaderSize = 4096
+
+var audioFormats []AudioFormat
+
+// Files with these extensions are expected next to audio (artwork, cue
+// sheets, rip logs) and are ignored without being reported as skipped.
+var ignoredExts = map[string]bool{
+	".jpg": true, ".jpeg": true, ".png": true, ".gif": true, ".bmp": true, ".webp": true,
+	".txt": true, ".nfo": true, ".cue": true, ".log": true, ".m3u": true, ".m3u8": true,
+	".pdf": true, ".sfv": true, ".md5": true, ".accurip": true, ".ini": true, ".db": true,
+}
+
+func init() {
+	registerAudioFormat(AudioFormat{
+		Name:       "FLAC",
+		Extensions: []string{".flac"},
+		Sniff:      func(b []byte) bool { return bytes.HasPrefix(b, []byte("fLaC")) },
+		TagTypes:   []tag.FileType{tag.FLAC},
+		Duration:   flacDuration,
+	})
+	registerAudioFormat(AudioFormat{
+		Name:       "WAV",
+		Extensions: []string{".wav", ".wave"},
+		Sniff: func(b []byte) bool {
+			return len(b) >= 12 && (string(b[0:4]) == "RIFF" || string(b[0:4]) == "RF64") && string(b[8:12]) == "WAVE"
+		},
+		Duration: wavDuration,
+	})
+	registerAudioFormat(AudioFormat{
+		Name:       "AIFF",
+		Extensions: []string{".aiff", ".aif", ".aifc"},
+		Sniff: func(b []byte) bool {
+			return len(b) >= 12 && string(b[0:4]) == "FORM" && (string(b[8:12]) == "AIFF" || string(b[8:12]) == "AIFC")
+		},
+		Duration: aiffDuration,
+	})
+	registerAudioFormat(AudioFormat{
+		Name:       "MPEG-4 Audio",
+		Extensions: []string{".m4a", ".alac", ".mp4", ".m4b"},
+		Sniff:      func(b []byte) bool { return mpeg4AudioBrands[mpeg4Brand(b)] },
+		TagTypes:   []tag.FileType{tag.M4A, tag.M4B, tag.ALAC},
+	})
+	registerAudioFormat(AudioFormat{
+		Name:       "Opus",
+		Extensions: []string{".opus"},
+		Sniff: func(b []byte) bool {
+			return bytes.HasPrefix(b, []byte("OggS")) && bytes.Contains(b[:min(len(b), 64)], []byte("OpusHead"))
+		},
+	})
+	registerAudioFormat(AudioFormat{
+		Name:       "Ogg Vorbis",
+		Extensions: []string{".ogg", ".oga"},
+		Sniff:      func(b []byte) bool { return bytes.HasPrefix(b, []byte("OggS")) },
+		TagTypes:   []tag.FileType{tag.OGG},
+	})
+	registerAudioFormat(AudioFormat{
+		Name:       "WavPack",
+		Extensions: []string{".wv"},
+		Sniff:      func(b []byte) bool { return bytes.HasPrefix(b, []byte("wvpk")) },
+	})
+	registerAudioFormat(AudioFormat{
+		Name:       "Monkey's Audio",
+		Extensions: []string{".ape"},
+		Sniff:      func(b []byte) bool { return bytes.HasPrefix(b, []byte("MAC ")) },
+	})
+	registerAudioFormat(AudioFormat{
+		Name:       "DSF",
+		Extensions: []string{".dsf"},
+		Sniff:      func(b []byte) bool { return bytes.HasPrefix(b, []byte("DSD ")) },
+		TagTypes:   []tag.FileType{tag.DSF},
+	})
+	registerAudioFormat(AudioFormat{
+		Name:       "AAC",
+		Extensions: []string{".aac"},
+		Sniff: func(b []byte) bool {
+			// ADTS frame sync: 12 set bits, MPEG layer bits zero.
+			return len(b) >= 2 && b[0] == 0xFF && b[1]&0xF6 == 0xF0
+		},
+	})
+	registerAudioFormat(AudioFormat{
+		Name:       "MP3",
+		Extensions: []string{".mp3"},
+		Sniff:      sniffMPEGAudio,
+		TagTypes:   []tag.FileType{tag.MP3},
+		Duration:   mp3Duration,
+	})
+}
+
+// registerAudioFormat adds a format to the scanner. Formats are sniffed in
+// registration order, so more specific signatures must be registered first.
+func registerAudioFormat(f AudioFormat) {
+	for i, ext := range f.Extensions {
+		f.Extensions[i] = strings.ToLower(ext)
+	}
+	audioFormats = append(audioFormats, f)
+}
+
+func formatByExtension(ext string) *AudioFormat {
+	ext = strings.ToLower(ext)
+	for i := range audioFormats {
+		for _, e := range audioFormats[i].Extensions {
+			if e == ext {
+				return &audioFormats[i]
+			}
+		}
+	}
+	return nil
+}
+
+func formatBySniff(header []byte) *AudioFormat {
+	for i := range audioFormats {
+		if audioFormats[i].Sniff != nil && audioFormats[i].Sniff(header) {
+			return &audioFormats[i]
+		}
+	}
+	return nil
+}
+
+func formatByTagType(ft tag.FileType) *AudioFormat {
+	for i := range audioFormats {
+		for _, t := range audioFormats[i].TagTypes {
+			if t == ft {
+				return &audioFormats[i]
+			}
+		}
+	}
+	return nil
+}
+
+// MPEG-4 is a container for photos (HEIC) and video too, so only these major
+// brands mark a file as audio by content alone. The generic brands are also
+// written by audio encoders and count when the file is named as audio.
+var (
+	mpeg4AudioBrands   = map[string]bool{"M4A ": true, "M4B ": true, "M4P ": true}
+	mpeg4GenericBrands = map[string]bool{"mp42": true, "isom": true}
+	mpeg4GenericExts   = map[string]bool{".m4a": true, ".mp4": true}
+)
+
+// mpeg4Brand returns the major brand of an ISO base media file, or "".
+func mpeg4Brand(b []byte) string {
+	if len(b) < 12 || string(b[4:8]) != "ftyp" {
+		return ""
+	}
+	return string(b[8:12])
+}
+
+func sniffMPEGAudio(b []byte) bool {
+	return len(b) >= 2 && isMPEGFrameSync(b[0], b[1])
+}
+
+func isMPEGFrameSync(b0 byte, b1 byte) bool {
+	return b0 == 0xFF && b1&0xE0 == 0xE0 && (b1>>1)&0x03 != 0
+}
+
+// hasMPEGFrameSync looks for a frame header near the start of the data.
+// Only used for files already named .mp3, since some rippers pad the start.
+func hasMPEGFrameSync(b []byte) bool {
+	limit := min(len(b)-1, 1024)
+	for i := 0; i < limit; i++ {
+		if isMPEGFrameSync(b[i], b[i+1]) {
+			return true
+		}
+	}
+	return false
+}
+
+// detectAudioFormat decides whether path is a supported audio file. The
+// content wins over the extension, so a FLAC saved as ".mp3" or a file with
+// an uppercase or missing extension is still recognised. When the file is
+// not picked up, the returned reason explains why.
+func detectAudioFormat(path string) (*AudioFormat, string) {
+	byExt := formatByExtension(filepath.Ext(path))
+
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Sprintf("cannot open file: %v", err)
+	}
+	defer f.Close()
+
+	header, hadID3, err := readAudioHeader(f)
+	if err != nil {
+		return nil, fmt.Sprintf("cannot read file: %v", err)
+	}
+	if len(header) == 0 && !hadID3 {
+		return nil, "file is empty"
+	}
+
+	if sniffed := formatBySniff(header); sniffed != nil {
+		return sniffed, ""
+	}
+	if mpeg4GenericBrands[mpeg4Brand(header)] && mpeg4GenericExts[strings.ToLower(filepath.Ext(path))] {
+		return byExt, ""
+	}
+
+	if _, err := f.Seek(0, io.SeekStart); err == nil {
+		if _, ft, err := tag.Identify(f); err == nil {
+			if identified := formatByTagType(ft); identified != nil {
+				return identified, ""
+			}
+		}
+	}
+
+	if byExt != nil && byExt.Name == "MP3" && (hadID3 || hasMPEGFrameSync(header)) {
+		return byExt, ""
+	}
+	if hadID3 && byExt == nil {
+		return formatByExtension(".mp3"), ""
+	}
+	if byExt != nil {
+		return nil, fmt.Sprintf("has %s extension but content is not recognised as %s", filepath.Ext(path), byExt.Name)
+	}
+	return nil, "not a supported audio format"
+}
+
+// readAudioHeader returns the first bytes of the audio stream, skipping a
+// leading ID3v2 tag (used by MP3 and sometimes prepended to FLAC and AAC).
+func readAudioHeader(f io.ReadSeeker) ([]byte, bool, error) {
+	buf := make([]byte, sniffHeaderSize)
+	n, err := io.ReadFull(f, buf)
+	if err != nil && err != io.ErrUnexpectedEOF && err != io.EOF {
+		return nil, false, err
+	}
+	buf = buf[:n]
+	if len(buf) < 10 || string(buf[0:3]) != "ID3" {
+		return buf, false, nil
+	}
+
+	if _, err := f.Seek(id3v2End(buf), io.SeekStart); err != nil {
+		return nil, true, nil
+	}
+	buf = make([]byte, sniffHeaderSize)
+	n, err = io.ReadFull(f, buf)
+	if err != nil && err != io.ErrUnexpectedEOF && err != io.EOF {
+		return nil, true, err
+	}
+	return buf[:n], true, nil
+}
+
+// id3v2End returns the offset just past the ID3v2 tag whose 10-byte header
+// starts head: the header, the syncsafe size and the footer, if present.
+func id3v2End(head []byte) int64 {
+	size := int64(head[6]&0x7F)<<21 | int64(head[7]&0x7F)<<14 | int64(head[8]&0x7F)<<7 | int64(head[9]&0x7F)
+	offset := 10 + size
+	if head[5]&0x10 != 0 {
+		offset += 10
+	}
+	return offset
+}
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// ftypHeader returns the start of an ISO base media file with the given
+// major brand.
+func ftypHeader(brand string) []byte {
+	box := []byte{0, 0, 0, 24}
+	box = append(box, "ftyp"+brand+"\x00\x00\x00\x00"+brand+"mp41"...)
+	return append(box, make([]byte, 64)...)
+}
+
+func TestDetectAudioFormat(t *testing.T) {
+	dir := t.TempDir()
+	for _, tc := range []struct {
+		name   string
+		header []byte
+		want   string // "" when the file must not be picked up
+	}{
+		{"song.m4a", ftypHeader("M4A "), "MPEG-4 Audio"},
+		{"book.m4b", ftypHeader("M4B "), "MPEG-4 Audio"},
+		{"song", ftypHeader("M4A "), "MPEG-4 Audio"},
+		{"song.mp4", ftypHeader("isom"), "MPEG-4 Audio"},
+		{"song.m4a", ftypHeader("mp42"), "MPEG-4 Audio"},
+		{"clip.mov", ftypHeader("qt  "), ""},
+		{"clip.mov", ftypHeader("isom"), ""},
+		{"clip", ftypHeader("mp42"), ""},
+		{"photo.heic", ftypHeader("heic"), ""},
+		{"photo.heic", ftypHeader("mif1"), ""},
+		{"photo.m4a", ftypHeader("heic"), ""},
+		{"song.mp3", append([]byte("fLaC"), make([]byte, 64)...), "FLAC"},
+		{"song.WAV", append([]byte("RIFF\x00\x00\x00\x00WAVE"), make([]byte, 64)...), "WAV"},
+	} {
+		path := filepath.Join(dir, tc.name)
+		if err := os.WriteFile(path, tc.header, 0o644); err != nil {
+			t.Fatal(err)
+		}
+		format, reason := detectAudioFormat(path)
+		got := ""
+		if format != nil {
+			got = format.Name
+		}
+		if got != tc.want {
+			t.Errorf("%s with %q: detected %q (%s), want %q", tc.name, tc.header[8:12], got, reason, tc.want)
+		}
+		if format == nil && reason == "" {
+			t.Errorf("%s: skipped without a reason", tc.name)
+		}
+	}
+}
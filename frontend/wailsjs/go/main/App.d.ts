@@ -2,12 +2,48 @@
 // This file is automatically generated. DO NOT EDIT
 import {main} from '../models';
 
-export function FetchAndMatchTracks(arg1:string,arg2:Array<main.LocalTrack>):Promise<Array<main.MatchedTrack>>;
+export function CancelJob(arg1:string):Promise<void>;
+
+export function CompareSources(arg1:Array<string>,arg2:Array<main.LocalTrack>):Promise<main.SourceComparison>;
+
+export function FetchAndMatchGroups(arg1:Array<main.AlbumGroup>,arg2:Record<string, string>,arg3:string):Promise<main.MatchResult>;
+
+export function FetchAndMatchTracks(arg1:string,arg2:Array<main.LocalTrack>,arg3:string):Promise<main.MatchResult>;
+
+export function GenerateAIRenames(arg1:Array<main.LocalTrack>,arg2:Array<main.AIParsedTrack>,arg3:string):Promise<Array<main.MatchedTrack>>;
+
+export function GenerateGroupRenames(arg1:Array<main.AlbumGroup>,arg2:string):Promise<Array<main.MatchedTrack>>;
 
 export function GenerateTemplateRenames(arg1:Array<main.LocalTrack>,arg2:string):Promise<Array<main.MatchedTrack>>;
 
+export function GetHTTPSettings():Promise<main.HTTPSettings>;
+
+export function ListReleases(arg1:string):Promise<Array<main.ReleaseSummary>>;
+
+export function ListRenameBatches():Promise<Array<main.RenameBatch>>;
+
+export function MatchMergedSources(arg1:main.SourceComparison,arg2:main.SourceChoices,arg3:Array<main.LocalTrack>,arg4:string):Promise<main.MatchResult>;
+
 export function ParseFilenamesWithAI(arg1:Array<string>,arg2:string):Promise<Array<main.AIParsedTrack>>;
 
-export function RenameMatchedTracks(arg1:Array<main.MatchedTrack>):Promise<string>;
+export function PickRelease(arg1:Array<main.ReleaseSummary>,arg2:Array<main.LocalTrack>):Promise<main.ReleaseSummary>;
+
+export function PreviewRenames(arg1:Array<main.MatchedTrack>,arg2:main.RenameOptions):Promise<Array<main.MatchedTrack>>;
+
+export function PurgeHTTPCache():Promise<number>;
+
+export function RenameMatchedTracks(arg1:Array<main.MatchedTrack>,arg2:main.RenameOptions):Promise<main.RenameReport>;
+
+export function SelectFolder():Promise<main.FolderScan>;
+
+export function SelectFolderRecursive():Promise<main.FolderScan>;
+
+export function SelectLibraryRoot():Promise<string>;
+
+export function SetHTTPSettings(arg1:main.HTTPSettings):Promise<void>;
+
+export function SuggestReleases(arg1:Array<main.LocalTrack>):Promise<Array<main.ReleaseSummary>>;
+
+export function UndoLastRename():Promise<main.UndoResult>;
 
-export function SelectFolder():Promise<Array<main.LocalTrack>>;
+export function UndoRename(arg1:string):Promise<main.UndoResult>;
@@ -2,22 +2,94 @@
 // Cynhyrchwyd y ffeil hon yn awtomatig. PEIDIWCH Â MODIWL
 // This file is automatically generated. DO NOT EDIT
 
-export function FetchAndMatchTracks(arg1, arg2) {
-  return window['go']['main']['App']['FetchAndMatchTracks'](arg1, arg2);
+export function CancelJob(arg1) {
+  return window['go']['main']['App']['CancelJob'](arg1);
+}
+
+export function CompareSources(arg1, arg2) {
+  return window['go']['main']['App']['CompareSources'](arg1, arg2);
+}
+
+export function FetchAndMatchGroups(arg1, arg2, arg3) {
+  return window['go']['main']['App']['FetchAndMatchGroups'](arg1, arg2, arg3);
+}
+
+export function FetchAndMatchTracks(arg1, arg2, arg3) {
+  return window['go']['main']['App']['FetchAndMatchTracks'](arg1, arg2, arg3);
+}
+
+export function GenerateAIRenames(arg1, arg2, arg3) {
+  return window['go']['main']['App']['GenerateAIRenames'](arg1, arg2, arg3);
+}
+
+export function GenerateGroupRenames(arg1, arg2) {
+  return window['go']['main']['App']['GenerateGroupRenames'](arg1, arg2);
 }
 
 export function GenerateTemplateRenames(arg1, arg2) {
   return window['go']['main']['App']['GenerateTemplateRenames'](arg1, arg2);
 }
 
+export function GetHTTPSettings() {
+  return window['go']['main']['App']['GetHTTPSettings']();
+}
+
+export function ListReleases(arg1) {
+  return window['go']['main']['App']['ListReleases'](arg1);
+}
+
+export function ListRenameBatches() {
+  return window['go']['main']['App']['ListRenameBatches']();
+}
+
+export function MatchMergedSources(arg1, arg2, arg3, arg4) {
+  return window['go']['main']['App']['MatchMergedSources'](arg1, arg2, arg3, arg4);
+}
+
 export function ParseFilenamesWithAI(arg1, arg2) {
   return window['go']['main']['App']['ParseFilenamesWithAI'](arg1, arg2);
 }
 
-export function RenameMatchedTracks(arg1) {
-  return window['go']['main']['App']['RenameMatchedTracks'](arg1);
+export function PickRelease(arg1, arg2) {
+  return window['go']['main']['App']['PickRelease'](arg1, arg2);
+}
+
+export function PreviewRenames(arg1, arg2) {
+  return window['go']['main']['App']['PreviewRenames'](arg1, arg2);
+}
+
+export function PurgeHTTPCache() {
+  return window['go']['main']['App']['PurgeHTTPCache']();
+}
+
+export function RenameMatchedTracks(arg1, arg2) {
+  return window['go']['main']['App']['RenameMatchedTracks'](arg1, arg2);
 }
 
 export function SelectFolder() {
   return window['go']['main']['App']['SelectFolder']();
 }
+
+export function SelectFolderRecursive() {
+  return window['go']['main']['App']['SelectFolderRecursive']();
+}
+
+export function SelectLibraryRoot() {
+  return window['go']['main']['App']['SelectLibraryRoot']();
+}
+
+export function SetHTTPSettings(arg1) {
+  return window['go']['main']['App']['SetHTTPSettings'](arg1);
+}
+
+export function SuggestReleases(arg1) {
+  return window['go']['main']['App']['SuggestReleases'](arg1);
+}
+
+export function UndoLastRename() {
+  return window['go']['main']['App']['UndoLastRename']();
+}
+
+export function UndoRename(arg1) {
+  return window['go']['main']['App']['UndoRename'](arg1);
+}
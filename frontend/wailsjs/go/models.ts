@@ -18,11 +18,225 @@ export namespace main {
 	        this.track_number = source["track_number"];
 	    }
 	}
+	export class AlbumData {
+	    artist: string;
+	    title: string;
+	    label: string;
+	    catalogNumber: string;
+	    year: number;
+	    releaseDate: string;
+	    discTotal: number;
+	    tracks: AlbumTrack[];
+	    source: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new AlbumData(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.artist = source["artist"];
+	        this.title = source["title"];
+	        this.label = source["label"];
+	        this.catalogNumber = source["catalogNumber"];
+	        this.year = source["year"];
+	        this.releaseDate = source["releaseDate"];
+	        this.discTotal = source["discTotal"];
+	        this.tracks = this.convertValues(source["tracks"], AlbumTrack);
+	        this.source = source["source"];
+	    }
+
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class AlbumGroup {
+	    dir: string;
+	    name: string;
+	    tracks: LocalTrack[];
+	
+	    static createFrom(source: any = {}) {
+	        return new AlbumGroup(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.dir = source["dir"];
+	        this.name = source["name"];
+	        this.tracks = this.convertValues(source["tracks"], LocalTrack);
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class AlbumTrack {
+	    title: string;
+	    artist: string;
+	    trackNum: number;
+	    trackNumExplicit: boolean;
+	    trackId: number;
+	    duration: number;
+	    discNum: number;
+	    position: string;
+	    credits: string;
+	    isrc: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new AlbumTrack(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.title = source["title"];
+	        this.artist = source["artist"];
+	        this.trackNum = source["trackNum"];
+	        this.trackNumExplicit = source["trackNumExplicit"];
+	        this.trackId = source["trackId"];
+	        this.duration = source["duration"];
+	        this.discNum = source["discNum"];
+	        this.position = source["position"];
+	        this.credits = source["credits"];
+	        this.isrc = source["isrc"];
+	    }
+	}
+	export class AlignedTrack {
+	    tracks: number[];
+	    localPath: string;
+	    originalName: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new AlignedTrack(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.tracks = source["tracks"];
+	        this.localPath = source["localPath"];
+	        this.originalName = source["originalName"];
+	    }
+	}
+	export class FolderScan {
+	    root: string;
+	    groups: AlbumGroup[];
+	    skipped: SkippedFile[];
+	
+	    static createFrom(source: any = {}) {
+	        return new FolderScan(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.root = source["root"];
+	        this.groups = this.convertValues(source["groups"], AlbumGroup);
+	        this.skipped = this.convertValues(source["skipped"], SkippedFile);
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class HTTPSettings {
+	    timeoutSeconds: number;
+	    retries: number;
+	    proxy: string;
+	    requestsPerSecond: number;
+	    userAgent: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new HTTPSettings(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.timeoutSeconds = source["timeoutSeconds"];
+	        this.retries = source["retries"];
+	        this.proxy = source["proxy"];
+	        this.requestsPerSecond = source["requestsPerSecond"];
+	        this.userAgent = source["userAgent"];
+	    }
+	}
+	export class JournalEntry {
+	    oldPath: string;
+	    newPath: string;
+	    method: string;
+	    replaced: boolean;
+	    undone: boolean;
+	
+	    static createFrom(source: any = {}) {
+	        return new JournalEntry(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.oldPath = source["oldPath"];
+	        this.newPath = source["newPath"];
+	        this.method = source["method"];
+	        this.replaced = source["replaced"];
+	        this.undone = source["undone"];
+	    }
+	}
 	export class LocalTrack {
 	    path: string;
 	    originalName: string;
 	    tagArtist: string;
 	    tagTitle: string;
+	    tagAlbum: string;
+	    tagAlbumArtist: string;
+	    tagTrack: number;
+	    tagTrackTotal: number;
+	    tagDisc: number;
+	    tagDiscTotal: number;
+	    tagYear: number;
+	    tagGenre: string;
+	    tagComposer: string;
+	    hasPicture: boolean;
+	    pictureMime: string;
+	    group: string;
+	    format: string;
+	    duration: number;
 	
 	    static createFrom(source: any = {}) {
 	        return new LocalTrack(source);
@@ -34,7 +248,83 @@ export namespace main {
 	        this.originalName = source["originalName"];
 	        this.tagArtist = source["tagArtist"];
 	        this.tagTitle = source["tagTitle"];
+	        this.tagAlbum = source["tagAlbum"];
+	        this.tagAlbumArtist = source["tagAlbumArtist"];
+	        this.tagTrack = source["tagTrack"];
+	        this.tagTrackTotal = source["tagTrackTotal"];
+	        this.tagDisc = source["tagDisc"];
+	        this.tagDiscTotal = source["tagDiscTotal"];
+	        this.tagYear = source["tagYear"];
+	        this.tagGenre = source["tagGenre"];
+	        this.tagComposer = source["tagComposer"];
+	        this.hasPicture = source["hasPicture"];
+	        this.pictureMime = source["pictureMime"];
+	        this.group = source["group"];
+	        this.format = source["format"];
+	        this.duration = source["duration"];
+	    }
+	}
+	export class MatchExplanation {
+	    signal: string;
+	    titleScore: number;
+	    fullScore: number;
+	    tagTitleScore: number;
+	    tagFullScore: number;
+	    trackNumberBonus: boolean;
+	    duration: string;
+	    durationDelta: number;
+	    runnerUp: string;
+	    runnerUpScore: number;
+	
+	    static createFrom(source: any = {}) {
+	        return new MatchExplanation(source);
 	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.signal = source["signal"];
+	        this.titleScore = source["titleScore"];
+	        this.fullScore = source["fullScore"];
+	        this.tagTitleScore = source["tagTitleScore"];
+	        this.tagFullScore = source["tagFullScore"];
+	        this.trackNumberBonus = source["trackNumberBonus"];
+	        this.duration = source["duration"];
+	        this.durationDelta = source["durationDelta"];
+	        this.runnerUp = source["runnerUp"];
+	        this.runnerUpScore = source["runnerUpScore"];
+	    }
+	}
+	export class MatchResult {
+	    tracks: MatchedTrack[];
+	    missing: MissingTrack[];
+	
+	    static createFrom(source: any = {}) {
+	        return new MatchResult(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.tracks = this.convertValues(source["tracks"], MatchedTrack);
+	        this.missing = this.convertValues(source["missing"], MissingTrack);
+	    }
+
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
 	}
 	export class MatchedTrack {
 	    localPath: string;
@@ -42,6 +332,8 @@ export namespace main {
 	    proposedNewName: string;
 	    confidence: number;
 	    status: string;
+	    warning: string;
+	    explanation?: MatchExplanation;
 	
 	    static createFrom(source: any = {}) {
 	        return new MatchedTrack(source);
@@ -54,8 +346,325 @@ export namespace main {
 	        this.proposedNewName = source["proposedNewName"];
 	        this.confidence = source["confidence"];
 	        this.status = source["status"];
+	        this.warning = source["warning"];
+	        this.explanation = this.convertValues(source["explanation"], MatchExplanation);
+	    }
+
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class MissingTrack {
+	    group: string;
+	    discNum: number;
+	    trackNum: number;
+	    artist: string;
+	    title: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new MissingTrack(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.group = source["group"];
+	        this.discNum = source["discNum"];
+	        this.trackNum = source["trackNum"];
+	        this.artist = source["artist"];
+	        this.title = source["title"];
+	    }
+	}
+	export class ReleaseSummary {
+	    provider: string;
+	    url: string;
+	    artist: string;
+	    title: string;
+	    label: string;
+	    trackCount: number;
+	    score: number;
+	
+	    static createFrom(source: any = {}) {
+	        return new ReleaseSummary(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.provider = source["provider"];
+	        this.url = source["url"];
+	        this.artist = source["artist"];
+	        this.title = source["title"];
+	        this.label = source["label"];
+	        this.trackCount = source["trackCount"];
+	        this.score = source["score"];
 	    }
 	}
+	export class RenameBatch {
+	    id: string;
+	    timestamp: any;
+	    method: string;
+	    libraryRoot: string;
+	    entries: JournalEntry[];
+	    undone: boolean;
+	
+	    static createFrom(source: any = {}) {
+	        return new RenameBatch(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.id = source["id"];
+	        this.timestamp = source["timestamp"];
+	        this.method = source["method"];
+	        this.libraryRoot = source["libraryRoot"];
+	        this.entries = this.convertValues(source["entries"], JournalEntry);
+	        this.undone = source["undone"];
+	    }
 
-}
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class RenameOptions {
+	    libraryRoot: string;
+	    cleanupEmptyDirs: boolean;
+	    sourceRoot: string;
+	    method: string;
+	    collisionPolicy: string;
+	    quarantineDir: string;
+	    filenameProfile: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new RenameOptions(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.libraryRoot = source["libraryRoot"];
+	        this.cleanupEmptyDirs = source["cleanupEmptyDirs"];
+	        this.sourceRoot = source["sourceRoot"];
+	        this.method = source["method"];
+	        this.collisionPolicy = source["collisionPolicy"];
+	        this.quarantineDir = source["quarantineDir"];
+	        this.filenameProfile = source["filenameProfile"];
+	    }
+	}
+	export class RenameReport {
+	    batchId: string;
+	    renamed: number;
+	    skipped: number;
+	    failed: number;
+	    results: RenameResult[];
+	    journalError: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new RenameReport(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.batchId = source["batchId"];
+	        this.renamed = source["renamed"];
+	        this.skipped = source["skipped"];
+	        this.failed = source["failed"];
+	        this.results = this.convertValues(source["results"], RenameResult);
+	        this.journalError = source["journalError"];
+	    }
+
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class RenameResult {
+	    localPath: string;
+	    originalName: string;
+	    proposedNewName: string;
+	    newPath: string;
+	    outcome: string;
+	    reason: string;
+	    collision: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new RenameResult(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.localPath = source["localPath"];
+	        this.originalName = source["originalName"];
+	        this.proposedNewName = source["proposedNewName"];
+	        this.newPath = source["newPath"];
+	        this.outcome = source["outcome"];
+	        this.reason = source["reason"];
+	        this.collision = source["collision"];
+	    }
+	}
+	export class SkippedFile {
+	    path: string;
+	    name: string;
+	    reason: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new SkippedFile(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.path = source["path"];
+	        this.name = source["name"];
+	        this.reason = source["reason"];
+	    }
+	}
+	export class SourceChoices {
+	    title: number;
+	    artist: number;
+	    numbering: number;
+	    album: number;
+	    label: number;
+	    year: number;
+	
+	    static createFrom(source: any = {}) {
+	        return new SourceChoices(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.title = source["title"];
+	        this.artist = source["artist"];
+	        this.numbering = source["numbering"];
+	        this.album = source["album"];
+	        this.label = source["label"];
+	        this.year = source["year"];
+	    }
+	}
+	export class SourceComparison {
+	    urls: string[];
+	    sources: AlbumData[];
+	    rows: AlignedTrack[];
+	
+	    static createFrom(source: any = {}) {
+	        return new SourceComparison(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.urls = source["urls"];
+	        this.sources = this.convertValues(source["sources"], AlbumData);
+	        this.rows = this.convertValues(source["rows"], AlignedTrack);
+	    }
 
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class UndoEntryResult {
+	    oldPath: string;
+	    newPath: string;
+	    restored: boolean;
+	    reason: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new UndoEntryResult(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.oldPath = source["oldPath"];
+	        this.newPath = source["newPath"];
+	        this.restored = source["restored"];
+	        this.reason = source["reason"];
+	    }
+	}
+	export class UndoResult {
+	    batchId: string;
+	    restored: number;
+	    failed: number;
+	    entries: UndoEntryResult[];
+	
+	    static createFrom(source: any = {}) {
+	        return new UndoResult(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.batchId = source["batchId"];
+	        this.restored = source["restored"];
+	        this.failed = source["failed"];
+	        this.entries = this.convertValues(source["entries"], UndoEntryResult);
+	    }
+
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+
+}
@@ -0,0 +1,205 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Fetched pages are cached on disk, one file per URL, in the user cache dir.
+// A fresh entry is used as is; a stale one is revalidated with its ETag or
+// Last-Modified date; and when the store cannot be reached at all, any
+// cached copy is used, so a release fetched once can be matched offline.
+
+const httpCacheTTL = 24 * time.Hour
+
+type httpCacheEntry struct {
+	StatusCode   int       `json:"statusCode"`
+	ContentType  string    `json:"contentType"`
+	ETag         string    `json:"etag"`
+	LastModified string    `json:"lastModified"`
+	FetchedAt    time.Time `json:"fetchedAt"`
+	Body         []byte    `json:"body"`
+}
+
+func httpCacheDir() (string, error) {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "AudioRenamer", "http"), nil
+}
+
+// httpCachePath names the entry for url after its hash, which keeps tokens in
+// query strings out of file names.
+func httpCachePath(url string) (string, error) {
+	dir, err := httpCacheDir()
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256([]byte(url))
+	return filepath.Join(dir, hex.EncodeToString(sum[:])+".json"), nil
+}
+
+func loadHTTPCacheEntry(url string) *httpCacheEntry {
+	path, err := httpCachePath(url)
+	if err != nil {
+		return nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil
+	}
+	var entry httpCacheEntry
+	if err := json.Unmarshal(data, &entry); err != nil {
+		return nil
+	}
+	return &entry
+}
+
+func saveHTTPCacheEntry(url string, entry *httpCacheEntry) error {
+	path, err := httpCachePath(url)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	// Write to a temp file of our own first, so neither a concurrent reader
+	// nor another writer of the same URL ever sees half an entry.
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	_, err = tmp.Write(data)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), path)
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+	}
+	return err
+}
+
+// response turns a cache entry back into a response for req.
+func (e *httpCacheEntry) response(req *http.Request) *http.Response {
+	header := http.Header{}
+	if e.ContentType != "" {
+		header.Set("Content-Type", e.ContentType)
+	}
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", e.StatusCode, http.StatusText(e.StatusCode)),
+		StatusCode:    e.StatusCode,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          io.NopCloser(bytes.NewReader(e.Body)),
+		ContentLength: int64(len(e.Body)),
+		Request:       req,
+	}
+}
+
+// cachedGet performs a GET through the cache, sending req with do when the
+// cache cannot answer. Only 200 responses are stored.
+func cachedGet(ctx context.Context, req *http.Request, do func(*http.Request) (*http.Response, error)) (*http.Response, error) {
+	url := req.URL.String()
+	entry := loadHTTPCacheEntry(url)
+	if entry != nil && time.Since(entry.FetchedAt) < httpCacheTTL {
+		return entry.response(req), nil
+	}
+	if entry != nil {
+		if entry.ETag != "" {
+			req.Header.Set("If-None-Match", entry.ETag)
+		}
+		if entry.LastModified != "" {
+			req.Header.Set("If-Modified-Since", entry.LastModified)
+		}
+	}
+
+	res, err := do(req)
+	if err != nil {
+		if entry != nil && ctx.Err() == nil {
+			log.Printf("Fetching %s failed (%v); using the copy cached %s", req.URL.Redacted(), err, entry.FetchedAt.Format(time.RFC3339))
+			return entry.response(req), nil
+		}
+		return nil, err
+	}
+
+	switch {
+	case res.StatusCode == http.StatusNotModified && entry != nil:
+		res.Body.Close()
+		entry.FetchedAt = time.Now()
+		if err := saveHTTPCacheEntry(url, entry); err != nil {
+			log.Printf("Failed to update cache entry: %v", err)
+		}
+		return entry.response(req), nil
+	case res.StatusCode == http.StatusOK:
+		body, err := io.ReadAll(res.Body)
+		res.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		fresh := &httpCacheEntry{
+			StatusCode:   res.StatusCode,
+			ContentType:  res.Header.Get("Content-Type"),
+			ETag:         res.Header.Get("ETag"),
+			LastModified: res.Header.Get("Last-Modified"),
+			FetchedAt:    time.Now(),
+			Body:         body,
+		}
+		if err := saveHTTPCacheEntry(url, fresh); err != nil {
+			log.Printf("Failed to cache %s: %v", req.URL.Redacted(), err)
+		}
+		return fresh.response(req), nil
+	case res.StatusCode >= 500 && entry != nil:
+		// The store is down; a stale page beats no page.
+		res.Body.Close()
+		log.Printf("%s returned %d; using the copy cached %s", req.URL.Redacted(), res.StatusCode, entry.FetchedAt.Format(time.RFC3339))
+		return entry.response(req), nil
+	}
+	return res, nil
+}
+
+// PurgeHTTPCache deletes every cached page and returns how many there were.
+func (a *App) PurgeHTTPCache() (int, error) {
+	dir, err := httpCacheDir()
+	if err != nil {
+		return 0, err
+	}
+	entries, err := os.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	removed := 0
+	for _, e := range entries {
+		if e.IsDir() || !strings.HasSuffix(e.Name(), ".json") {
+			continue
+		}
+		if err := os.Remove(filepath.Join(dir, e.Name())); err != nil {
+			return removed, fmt.Errorf("failed to remove cache entry: %w", err)
+		}
+		removed++
+	}
+	return removed, nil
+}
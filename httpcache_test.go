@@ -0,0 +1,131 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// getThroughCache fetches url through the cache and returns the status and
+// body the caller sees.
+func getThroughCache(t *testing.T, url string) (int, string) {
+	t.Helper()
+	req, _ := http.NewRequest("GET", url, nil)
+	res, err := cachedGet(context.Background(), req, http.DefaultClient.Do)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Body.Close()
+	body, _ := io.ReadAll(res.Body)
+	return res.StatusCode, string(body)
+}
+
+// ageCacheEntry makes the entry for url stale.
+func ageCacheEntry(t *testing.T, url string) {
+	t.Helper()
+	entry := loadHTTPCacheEntry(url)
+	if entry == nil {
+		t.Fatal("page was not cached")
+	}
+	entry.FetchedAt = time.Now().Add(-2 * httpCacheTTL)
+	if err := saveHTTPCacheEntry(url, entry); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestHTTPCacheRevalidation(t *testing.T) {
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+	var calls atomic.Int32
+	var status atomic.Int32
+	status.Store(http.StatusOK)
+	var conditional atomic.Value
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		conditional.Store(r.Header.Get("If-None-Match") + "|" + r.Header.Get("If-Modified-Since"))
+		if r.Header.Get("If-None-Match") == `"v1"` && status.Load() == http.StatusOK {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("ETag", `"v1"`)
+		w.Header().Set("Last-Modified", "Mon, 12 Oct 2026 10:00:00 GMT")
+		w.WriteHeader(int(status.Load()))
+		fmt.Fprint(w, "release page")
+	}))
+	defer srv.Close()
+	url := srv.URL + "/release/1"
+
+	if code, body := getThroughCache(t, url); code != http.StatusOK || body != "release page" {
+		t.Fatalf("first fetch: %d %q", code, body)
+	}
+	// A fresh entry is served without asking the store.
+	getThroughCache(t, url)
+	if calls.Load() != 1 {
+		t.Errorf("%d requests for a fresh entry, want 1", calls.Load())
+	}
+
+	// A stale entry is revalidated, and a 304 serves the cached body.
+	ageCacheEntry(t, url)
+	if code, body := getThroughCache(t, url); code != http.StatusOK || body != "release page" {
+		t.Errorf("after 304: %d %q", code, body)
+	}
+	if got := conditional.Load(); got != `"v1"|Mon, 12 Oct 2026 10:00:00 GMT` {
+		t.Errorf("conditional headers = %q", got)
+	}
+	if entry := loadHTTPCacheEntry(url); time.Since(entry.FetchedAt) > time.Minute {
+		t.Error("304 did not refresh the entry")
+	}
+
+	// A store error falls back to the stale copy, and so does a store that
+	// cannot be reached at all.
+	ageCacheEntry(t, url)
+	status.Store(http.StatusServiceUnavailable)
+	if code, body := getThroughCache(t, url); code != http.StatusOK || body != "release page" {
+		t.Errorf("after 503: %d %q", code, body)
+	}
+	srv.Close()
+	if code, body := getThroughCache(t, url); code != http.StatusOK || body != "release page" {
+		t.Errorf("offline: %d %q", code, body)
+	}
+}
+
+func TestHTTPCacheConcurrentWrites(t *testing.T) {
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+	const url = "https://www.example.org/release/1"
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			body := make([]byte, 64*1024)
+			for j := range body {
+				body[j] = byte('a' + i)
+			}
+			if err := saveHTTPCacheEntry(url, &httpCacheEntry{StatusCode: 200, FetchedAt: time.Now(), Body: body}); err != nil {
+				t.Error(err)
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	entry := loadHTTPCacheEntry(url)
+	if entry == nil || len(entry.Body) != 64*1024 {
+		t.Fatal("cache entry is corrupt")
+	}
+	for _, b := range entry.Body {
+		if b != entry.Body[0] {
+			t.Fatal("cache entry mixes two writes")
+		}
+	}
+	dir, _ := httpCacheDir()
+	files, _ := os.ReadDir(dir)
+	if len(files) != 1 {
+		t.Errorf("%d files in the cache, want 1 (temp files left behind?)", len(files))
+	}
+}
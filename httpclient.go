@@ -0,0 +1,306 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"math/rand"
+	"net/http"
+	neturl "net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// All outgoing requests (store pages, APIs, Gemini) go through one shared
+// client so a hung connection cannot freeze the app, transient failures are
+// retried, and no store is hammered. The frontend keeps the settings and
+// pushes them with SetHTTPSettings.
+
+// HTTPSettings configures the shared client. Zero values mean the defaults.
+type HTTPSettings struct {
+	// TimeoutSeconds bounds each attempt, including reading the body, for
+	// requests whose context has no deadline of its own.
+	TimeoutSeconds int `json:"timeoutSeconds"`
+	// Retries is how many times a request is repeated after a network error,
+	// a 429 or a 5xx response; -1 turns retrying off.
+	Retries int `json:"retries"`
+	// Proxy is an http://, https:// or socks5:// URL. Empty uses the
+	// HTTP_PROXY/HTTPS_PROXY environment variables.
+	Proxy string `json:"proxy"`
+	// RequestsPerSecond limits requests to each host.
+	RequestsPerSecond float64 `json:"requestsPerSecond"`
+	// UserAgent replaces the app's own identification; empty keeps it.
+	UserAgent string `json:"userAgent"`
+}
+
+// appVersion is info.productVersion from wails.json, the version Wails also
+// stamps into the Windows and macOS bundles.
+var appVersion = productVersion(wailsConfig)
+
+// Stores that publish a rate limit are held to it whatever the settings say.
+// MusicBrainz allows one request per second.
+var hostRequestLimits = map[string]float64{
+	"musicbrainz.org": 1,
+}
+
+const (
+	defaultHTTPTimeoutSeconds = 30
+	defaultHTTPRetries        = 2
+	defaultRequestsPerSecond  = 2
+	maxHTTPRetries            = 5
+	retryBaseDelay            = 500 * time.Millisecond
+	maxRetryDelay             = 30 * time.Second
+)
+
+type httpClient struct {
+	settings HTTPSettings
+	client   *http.Client
+
+	mu       sync.Mutex
+	nextSlot map[string]time.Time
+}
+
+var (
+	sharedHTTPMu sync.RWMutex
+	sharedHTTP   = mustHTTPClient(HTTPSettings{})
+)
+
+// withDefaults fills in the defaults for unset fields. An empty UserAgent
+// stays empty, so the app's own one is used even after an upgrade, and
+// Retries stays -1, so retrying is still off once the frontend saves the
+// settings and pushes them back.
+func (s HTTPSettings) withDefaults() HTTPSettings {
+	if s.TimeoutSeconds <= 0 {
+		s.TimeoutSeconds = defaultHTTPTimeoutSeconds
+	}
+	if s.Retries < 0 {
+		s.Retries = -1
+	} else if s.Retries == 0 {
+		s.Retries = defaultHTTPRetries
+	}
+	s.Retries = min(s.Retries, maxHTTPRetries)
+	if s.RequestsPerSecond <= 0 {
+		s.RequestsPerSecond = defaultRequestsPerSecond
+	}
+	s.Proxy = strings.TrimSpace(s.Proxy)
+	s.UserAgent = strings.TrimSpace(s.UserAgent)
+	return s
+}
+
+func newHTTPClient(settings HTTPSettings) (*httpClient, error) {
+	settings = settings.withDefaults()
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	if settings.Proxy != "" {
+		proxy, err := neturl.Parse(settings.Proxy)
+		if err != nil {
+			return nil, fmt.Errorf("invalid proxy URL: %w", err)
+		}
+		switch proxy.Scheme {
+		case "http", "https", "socks5", "socks5h":
+		default:
+			return nil, fmt.Errorf("unsupported proxy scheme %q; use http, https or socks5", proxy.Scheme)
+		}
+		transport.Proxy = http.ProxyURL(proxy)
+	}
+	return &httpClient{
+		settings: settings,
+		client:   &http.Client{Transport: transport},
+		nextSlot: map[string]time.Time{},
+	}, nil
+}
+
+func mustHTTPClient(settings HTTPSettings) *httpClient {
+	c, err := newHTTPClient(settings)
+	if err != nil {
+		panic(err)
+	}
+	return c
+}
+
+// sharedHTTPClient returns the client built from the current settings.
+func sharedHTTPClient() *httpClient {
+	sharedHTTPMu.RLock()
+	defer sharedHTTPMu.RUnlock()
+	return sharedHTTP
+}
+
+// SetHTTPSettings replaces the shared client's settings.
+func (a *App) SetHTTPSettings(settings HTTPSettings) error {
+	c, err := newHTTPClient(settings)
+	if err != nil {
+		return err
+	}
+	sharedHTTPMu.Lock()
+	old := sharedHTTP
+	sharedHTTP = c
+	sharedHTTPMu.Unlock()
+	old.client.CloseIdleConnections()
+	return nil
+}
+
+// GetHTTPSettings returns the settings in effect, defaults filled in except
+// for the user agent.
+func (a *App) GetHTTPSettings() HTTPSettings {
+	return sharedHTTPClient().settings
+}
+
+// Do sends req, waiting for the host's rate limit and retrying network
+// errors, 429 and 5xx responses with exponential backoff. A Retry-After
+// header is honoured. Requests with a body are only retried if the body can
+// be replayed (GetBody is set).
+func (c *httpClient) Do(req *http.Request) (*http.Response, error) {
+	if req.Header.Get("User-Agent") == "" {
+		req.Header.Set("User-Agent", firstNonEmpty(c.settings.UserAgent, defaultUserAgent()))
+	}
+	ctx := req.Context()
+	for attempt := 0; ; attempt++ {
+		if err := c.waitForHost(ctx, req.URL.Hostname()); err != nil {
+			return nil, err
+		}
+		res, err := c.send(req)
+		canRetry := attempt < max(c.settings.Retries, 0) && (req.Body == nil || req.GetBody != nil) && ctx.Err() == nil
+		if err == nil && !retryableStatus(res.StatusCode) || !canRetry {
+			return res, err
+		}
+
+		delay := retryBaseDelay << attempt
+		if err != nil {
+			log.Printf("Request to %s failed (%v); retrying", req.URL.Host, withoutURL(err))
+		} else {
+			if after := retryAfter(res.Header.Get("Retry-After")); after > 0 {
+				delay = after
+			}
+			log.Printf("%s returned %d; retrying", req.URL.Host, res.StatusCode)
+			res.Body.Close()
+		}
+		delay = min(delay+time.Duration(rand.Int63n(int64(delay)/4+1)), maxRetryDelay)
+		if err := sleepContext(ctx, delay); err != nil {
+			return nil, err
+		}
+		if req.GetBody != nil {
+			body, err := req.GetBody()
+			if err != nil {
+				return nil, err
+			}
+			req = req.Clone(ctx)
+			req.Body = body
+		}
+	}
+}
+
+// send makes one attempt. Unless the request already has a deadline, the
+// attempt gets the configured timeout; it stays in force until the body is
+// closed.
+func (c *httpClient) send(req *http.Request) (*http.Response, error) {
+	if _, ok := req.Context().Deadline(); ok {
+		return c.client.Do(req)
+	}
+	ctx, cancel := context.WithTimeout(req.Context(), time.Duration(c.settings.TimeoutSeconds)*time.Second)
+	res, err := c.client.Do(req.WithContext(ctx))
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	res.Body = &cancelOnClose{ReadCloser: res.Body, cancel: cancel}
+	return res, nil
+}
+
+type cancelOnClose struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (b *cancelOnClose) Close() error {
+	err := b.ReadCloser.Close()
+	b.cancel()
+	return err
+}
+
+// withoutURL strips the URL a *url.Error carries, since query strings can
+// hold credentials.
+func withoutURL(err error) error {
+	var urlErr *neturl.Error
+	if errors.As(err, &urlErr) {
+		return urlErr.Err
+	}
+	return err
+}
+
+func retryableStatus(code int) bool {
+	return code == http.StatusTooManyRequests || code >= 500
+}
+
+// retryAfter reads a Retry-After header given in seconds or as a date.
+func retryAfter(value string) time.Duration {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0
+	}
+	if secs, err := strconv.Atoi(value); err == nil {
+		return time.Duration(secs) * time.Second
+	}
+	if t, err := http.ParseTime(value); err == nil {
+		return time.Until(t)
+	}
+	return 0
+}
+
+// productVersion reads info.productVersion from a wails.json, or "dev" when
+// it is not set.
+func productVersion(config []byte) string {
+	var project struct {
+		Info struct {
+			ProductVersion string `json:"productVersion"`
+		} `json:"info"`
+	}
+	if err := json.Unmarshal(config, &project); err != nil {
+		log.Printf("Failed to read the version from wails.json: %v", err)
+	}
+	return firstNonEmpty(strings.TrimSpace(project.Info.ProductVersion), "dev")
+}
+
+// defaultUserAgent identifies the app, as MusicBrainz requires.
+func defaultUserAgent() string {
+	return "AudioRenamer/" + appVersion + " (+https://github.com/0x800700/AudioRenamer)"
+}
+
+// waitForHost spaces requests to host at least 1/RequestsPerSecond apart, or
+// further if the store asks for it.
+func (c *httpClient) waitForHost(ctx context.Context, host string) error {
+	rate := c.settings.RequestsPerSecond
+	for domain, limit := range hostRequestLimits {
+		if hostIs(strings.ToLower(host), domain) {
+			rate = min(rate, limit)
+		}
+	}
+	interval := time.Duration(float64(time.Second) / rate)
+	c.mu.Lock()
+	now := time.Now()
+	slot := c.nextSlot[host]
+	if slot.Before(now) {
+		slot = now
+	}
+	c.nextSlot[host] = slot.Add(interval)
+	c.mu.Unlock()
+	return sleepContext(ctx, time.Until(slot))
+}
+
+// sleepContext waits for d, or until ctx is cancelled.
+func sleepContext(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return ctx.Err()
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,134 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestHTTPSettingsDefaults(t *testing.T) {
+	got := HTTPSettings{UserAgent: "  ", Retries: 9}.withDefaults()
+	want := HTTPSettings{TimeoutSeconds: defaultHTTPTimeoutSeconds, Retries: maxHTTPRetries, RequestsPerSecond: defaultRequestsPerSecond}
+	if got != want {
+		t.Errorf("withDefaults = %+v, want %+v", got, want)
+	}
+	if got := (HTTPSettings{Retries: -3}).withDefaults().Retries; got != -1 {
+		t.Errorf("Retries -3 gives %d, want -1", got)
+	}
+	if _, err := newHTTPClient(HTTPSettings{Proxy: "ftp://proxy"}); err == nil {
+		t.Error("ftp proxy accepted")
+	}
+}
+
+func TestProductVersion(t *testing.T) {
+	for config, want := range map[string]string{
+		`{"name": "AudioRenamer", "info": {"productVersion": "1.4.2"}}`: "1.4.2",
+		`{"name": "AudioRenamer"}`:                                      "dev",
+		`{`:                                                             "dev",
+	} {
+		if got := productVersion([]byte(config)); got != want {
+			t.Errorf("%s: got %q, want %q", config, got, want)
+		}
+	}
+	if appVersion == "dev" {
+		t.Error("wails.json has no info.productVersion")
+	}
+}
+
+func TestHTTPClientRetriesAndUserAgent(t *testing.T) {
+	var calls atomic.Int32
+	var agent atomic.Value
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		agent.Store(r.UserAgent())
+		if calls.Add(1) < 2 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.Write([]byte("ok"))
+	}))
+	defer srv.Close()
+
+	c := mustHTTPClient(HTTPSettings{Retries: 2, RequestsPerSecond: 1000})
+	req, _ := http.NewRequest("GET", srv.URL, nil)
+	res, err := c.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.Body.Close()
+	if res.StatusCode != http.StatusOK || calls.Load() != 2 {
+		t.Errorf("status %d after %d calls, want 200 after 2", res.StatusCode, calls.Load())
+	}
+	if ua := agent.Load().(string); !strings.HasPrefix(ua, "AudioRenamer/"+appVersion+" ") {
+		t.Errorf("user agent = %q", ua)
+	}
+}
+
+func TestHTTPSettingsRetriesOffSurvivesSave(t *testing.T) {
+	old := sharedHTTPClient()
+	defer func() {
+		sharedHTTPMu.Lock()
+		sharedHTTP = old
+		sharedHTTPMu.Unlock()
+	}()
+
+	// The frontend saves what GetHTTPSettings returns and pushes it back on
+	// the next start.
+	a := &App{}
+	if err := a.SetHTTPSettings(HTTPSettings{Retries: -1}); err != nil {
+		t.Fatal(err)
+	}
+	if err := a.SetHTTPSettings(a.GetHTTPSettings()); err != nil {
+		t.Fatal(err)
+	}
+	if got := a.GetHTTPSettings().Retries; got != -1 {
+		t.Fatalf("Retries = %d after a round trip, want -1", got)
+	}
+
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer srv.Close()
+	req, _ := http.NewRequest("GET", srv.URL, nil)
+	res, err := sharedHTTPClient().Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.Body.Close()
+	if calls.Load() != 1 {
+		t.Errorf("%d calls with retries off, want 1", calls.Load())
+	}
+}
+
+func TestWaitForHostHonoursStoreLimit(t *testing.T) {
+	c := mustHTTPClient(HTTPSettings{RequestsPerSecond: 100})
+	ctx := context.Background()
+	c.waitForHost(ctx, "musicbrainz.org")
+	start := time.Now()
+	c.waitForHost(ctx, "www.musicbrainz.org")
+	c.waitForHost(ctx, "example.org")
+	c.waitForHost(ctx, "musicbrainz.org")
+	if waited := time.Since(start); waited < 900*time.Millisecond {
+		t.Errorf("second MusicBrainz request after %v, want about 1s", waited)
+	}
+
+	start = time.Now()
+	c.waitForHost(ctx, "example.org")
+	if waited := time.Since(start); waited > 100*time.Millisecond {
+		t.Errorf("example.org waited %v", waited)
+	}
+}
+
+func TestRetryLogOmitsURL(t *testing.T) {
+	err := &url.Error{Op: "Post", URL: "https://example.org/?key=secret", Err: errors.New("connection reset")}
+	if got := withoutURL(err).Error(); strings.Contains(got, "secret") {
+		t.Errorf("withoutURL = %q", got)
+	}
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+
+	"github.com/wailsapp/wails/v2/pkg/runtime"
+)
+
+// Slow operations (scanning, store fetches, AI parsing, renaming) run as
+// jobs. Each job gets an ID, announced with a "job:started" event before any
+// work is done, and a context that CancelJob cancels. Cancelling stops the
+// job, not the app.
+
+const (
+	jobStartedEvent  = "job:started"
+	jobFinishedEvent = "job:finished"
+)
+
+// JobInfo is the payload of the job events.
+type JobInfo struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+var errJobCancelled = errors.New("operation cancelled")
+
+// startJob registers a job and returns its context and the function that
+// ends it, which must be called when the operation returns.
+func (a *App) startJob(name string) (context.Context, func()) {
+	ctx, cancel := context.WithCancel(a.context())
+	a.jobsMu.Lock()
+	a.jobSeq++
+	job := JobInfo{ID: strconv.FormatUint(a.jobSeq, 10), Name: name}
+	if a.jobs == nil {
+		a.jobs = make(map[string]context.CancelFunc)
+	}
+	a.jobs[job.ID] = cancel
+	a.jobsMu.Unlock()
+	a.emit(jobStartedEvent, job)
+
+	return ctx, func() {
+		a.jobsMu.Lock()
+		delete(a.jobs, job.ID)
+		a.jobsMu.Unlock()
+		cancel()
+		a.emit(jobFinishedEvent, job)
+	}
+}
+
+// CancelJob cancels a running job. The job's binding returns soon after,
+// with an "operation cancelled" error or, for renames, a report in which the
+// files not yet renamed are skipped.
+func (a *App) CancelJob(id string) error {
+	a.jobsMu.Lock()
+	cancel, ok := a.jobs[id]
+	a.jobsMu.Unlock()
+	if !ok {
+		return fmt.Errorf("no running job %s", id)
+	}
+	log.Printf("Cancelling job %s", id)
+	cancel()
+	return nil
+}
+
+// cancelledErr reports err as errJobCancelled when ctx was cancelled, since
+// the error is then only a consequence of the cancellation.
+func cancelledErr(ctx context.Context, err error) error {
+	if err != nil && ctx.Err() != nil {
+		return errJobCancelled
+	}
+	return err
+}
+
+// emit sends an event to the frontend. Before startup there is none.
+func (a *App) emit(event string, data interface{}) {
+	if a.ctx != nil {
+		runtime.EventsEmit(a.ctx, event, data)
+	}
+}
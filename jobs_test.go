@@ -0,0 +1,37 @@
+package main
+
+import "testing"
+
+func TestCancelJobLeavesOtherJobsRunning(t *testing.T) {
+	a := &App{}
+	scanCtx, scanDone := a.startJob("scan")
+	defer scanDone()
+	fetchCtx, fetchDone := a.startJob("fetch")
+	defer fetchDone()
+
+	if err := a.CancelJob("1"); err != nil {
+		t.Fatal(err)
+	}
+	if scanCtx.Err() == nil {
+		t.Error("cancelled job is still running")
+	}
+	if fetchCtx.Err() != nil {
+		t.Error("cancelling one job cancelled another")
+	}
+	if err := a.CancelJob("3"); err == nil {
+		t.Error("cancelling an unknown job returned no error")
+	}
+}
+
+func TestFinishedJobCannotBeCancelled(t *testing.T) {
+	a := &App{}
+	ctx, done := a.startJob("rename")
+	done()
+
+	if ctx.Err() == nil {
+		t.Error("finished job's context is still live")
+	}
+	if err := a.CancelJob("1"); err == nil {
+		t.Error("cancelling a finished job returned no error")
+	}
+}
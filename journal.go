@@ -0,0 +1,258 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+)
+
+// The rename journal records every applied batch so it can be undone later,
+// even after the app has been restarted. It lives in the user config dir.
+
+const maxJournalBatches = 50
+
+type RenameBatch struct {
+	ID          string         `json:"id"`
+	Timestamp   time.Time      `json:"timestamp"`
+	Method      string         `json:"method"`
+	LibraryRoot string         `json:"libraryRoot"`
+	Entries     []JournalEntry `json:"entries"`
+	Undone      bool           `json:"undone"`
+}
+
+type JournalEntry struct {
+	OldPath  string `json:"oldPath"`
+	NewPath  string `json:"newPath"`
+	Method   string `json:"method"`
+	Replaced bool   `json:"replaced"`
+	Undone   bool   `json:"undone"`
+}
+
+type UndoEntryResult struct {
+	OldPath  string `json:"oldPath"`
+	NewPath  string `json:"newPath"`
+	Restored bool   `json:"restored"`
+	Reason   string `json:"reason"`
+}
+
+type UndoResult struct {
+	BatchID  string            `json:"batchId"`
+	Restored int               `json:"restored"`
+	Failed   int               `json:"failed"`
+	Entries  []UndoEntryResult `json:"entries"`
+}
+
+func journalPath() (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "AudioRenamer", "rename-journal.json"), nil
+}
+
+func loadJournal() ([]RenameBatch, error) {
+	path, err := journalPath()
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return []RenameBatch{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var batches []RenameBatch
+	if err := json.Unmarshal(data, &batches); err != nil {
+		return nil, fmt.Errorf("failed to read rename journal: %w", err)
+	}
+	return batches, nil
+}
+
+func saveJournal(batches []RenameBatch) error {
+	path, err := journalPath()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	if len(batches) > maxJournalBatches {
+		batches = batches[len(batches)-maxJournalBatches:]
+	}
+	data, err := json.MarshalIndent(batches, "", "  ")
+	if err != nil {
+		return err
+	}
+	// Write to a temp file first so a crash never leaves a truncated journal.
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+func newRenameBatch(method string, libraryRoot string) RenameBatch {
+	now := time.Now()
+	return RenameBatch{
+		ID:          strconv.FormatInt(now.UnixNano(), 36),
+		Timestamp:   now,
+		Method:      method,
+		LibraryRoot: libraryRoot,
+	}
+}
+
+func (a *App) appendJournal(batch RenameBatch) error {
+	if len(batch.Entries) == 0 {
+		return nil
+	}
+	a.journalMu.Lock()
+	defer a.journalMu.Unlock()
+	batches, err := loadJournal()
+	if err != nil {
+		return err
+	}
+	return saveJournal(append(batches, batch))
+}
+
+// ListRenameBatches returns the journal, newest batch first.
+func (a *App) ListRenameBatches() ([]RenameBatch, error) {
+	a.journalMu.Lock()
+	defer a.journalMu.Unlock()
+	batches, err := loadJournal()
+	if err != nil {
+		return nil, err
+	}
+	for i, j := 0, len(batches)-1; i < j; i, j = i+1, j-1 {
+		batches[i], batches[j] = batches[j], batches[i]
+	}
+	return batches, nil
+}
+
+// UndoLastRename reverses the most recent batch that has not been undone.
+func (a *App) UndoLastRename() (*UndoResult, error) {
+	a.journalMu.Lock()
+	defer a.journalMu.Unlock()
+	batches, err := loadJournal()
+	if err != nil {
+		return nil, err
+	}
+	for i := len(batches) - 1; i >= 0; i-- {
+		if !batches[i].Undone {
+			return undoBatch(batches, i)
+		}
+	}
+	return nil, fmt.Errorf("nothing to undo")
+}
+
+// UndoRename reverses the batch with the given journal ID.
+func (a *App) UndoRename(id string) (*UndoResult, error) {
+	a.journalMu.Lock()
+	defer a.journalMu.Unlock()
+	batches, err := loadJournal()
+	if err != nil {
+		return nil, err
+	}
+	for i := range batches {
+		if batches[i].ID == id {
+			if batches[i].Undone {
+				return nil, fmt.Errorf("rename batch %s has already been undone", id)
+			}
+			return undoBatch(batches, i)
+		}
+	}
+	return nil, fmt.Errorf("rename batch %s not found", id)
+}
+
+// undoBatch moves files back. The moves are planned as one batch, so a swap
+// or chain is reversed correctly. Entries whose file is no longer at the new
+// path, or whose original path has been taken by something else, are left
+// alone and reported; they can be retried after the user fixes them.
+func undoBatch(batches []RenameBatch, index int) (*UndoResult, error) {
+	batch := &batches[index]
+	result := &UndoResult{BatchID: batch.ID, Entries: []UndoEntryResult{}}
+
+	var moves []*fileMove
+	var entries []*JournalEntry
+	var replaced []*JournalEntry
+	for i := len(batch.Entries) - 1; i >= 0; i-- {
+		entry := &batch.Entries[i]
+		if entry.Undone {
+			continue
+		}
+		if entry.Replaced {
+			replaced = append(replaced, entry)
+			continue
+		}
+		moves = append(moves, &fileMove{From: entry.NewPath, To: entry.OldPath})
+		entries = append(entries, entry)
+	}
+	runMoves(context.Background(), moves, collisionPolicy{Mode: collisionSkip})
+
+	// A rename that replaced an identical file is undone by copying, so the
+	// file that was already at the target stays where it was.
+	for _, entry := range replaced {
+		res := UndoEntryResult{OldPath: entry.OldPath, NewPath: entry.NewPath}
+		if _, err := os.Stat(entry.NewPath); err != nil {
+			res.Reason = "file is no longer at its renamed location"
+		} else if _, err := os.Stat(entry.OldPath); err == nil {
+			res.Reason = "original location is already taken"
+		} else if err := os.MkdirAll(filepath.Dir(entry.OldPath), 0o755); err != nil {
+			res.Reason = err.Error()
+		} else if err := copyFile(entry.NewPath, entry.OldPath); err != nil {
+			res.Reason = err.Error()
+		} else {
+			res.Restored = true
+			entry.Undone = true
+			result.Restored++
+		}
+		if !res.Restored {
+			result.Failed++
+		}
+		result.Entries = append(result.Entries, res)
+	}
+
+	for i, m := range moves {
+		entry := entries[i]
+		res := UndoEntryResult{OldPath: entry.OldPath, NewPath: entry.NewPath}
+		switch m.Outcome {
+		case outcomeRenamed:
+			res.Restored = true
+			entry.Undone = true
+			result.Restored++
+			if entry.Method == "Quarantine" {
+				removeEmptyDirs(filepath.Dir(entry.NewPath), "")
+			} else if batch.LibraryRoot != "" {
+				removeEmptyDirs(filepath.Dir(entry.NewPath), batch.LibraryRoot)
+			}
+		case outcomeSkippedCollision:
+			res.Reason = "original location is already taken"
+		default:
+			if _, err := os.Stat(entry.NewPath); err != nil {
+				res.Reason = "file is no longer at its renamed location"
+			} else {
+				res.Reason = m.Reason
+			}
+		}
+		if !res.Restored {
+			result.Failed++
+		}
+		result.Entries = append(result.Entries, res)
+	}
+
+	batch.Undone = true
+	for _, entry := range batch.Entries {
+		if !entry.Undone {
+			batch.Undone = false
+			break
+		}
+	}
+	if err := saveJournal(batches); err != nil {
+		return result, fmt.Errorf("files were restored but the journal could not be updated: %w", err)
+	}
+	return result, nil
+}
@@ -0,0 +1,141 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// renameForUndo renames files in dir (old name to new name) through
+// RenameMatchedTracks, so the batch is journaled as the app does it.
+func renameForUndo(t *testing.T, a *App, dir string, options RenameOptions, pairs ...string) *RenameReport {
+	t.Helper()
+	var tracks []MatchedTrack
+	for i := 0; i+1 < len(pairs); i += 2 {
+		tracks = append(tracks, MatchedTrack{
+			LocalPath:       filepath.Join(dir, pairs[i]),
+			OriginalName:    pairs[i],
+			ProposedNewName: pairs[i+1],
+			Status:          statusMatched,
+		})
+	}
+	report, err := a.RenameMatchedTracks(tracks, options)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.JournalError != "" {
+		t.Fatal(report.JournalError)
+	}
+	return report
+}
+
+func TestUndoRenameRoundTrip(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{"a.flac": "a", "b.flac": "b", "c.flac": "c"})
+	a := &App{}
+
+	first := renameForUndo(t, a, dir, RenameOptions{}, "a.flac", "b.flac", "b.flac", "a.flac")
+	second := renameForUndo(t, a, dir, RenameOptions{}, "c.flac", "03. C.flac")
+	checkFiles(t, dir, map[string]string{"a.flac": "b", "b.flac": "a", "03. C.flac": "c"})
+
+	batches, err := a.ListRenameBatches()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(batches) != 2 || batches[0].ID != second.BatchID || batches[1].ID != first.BatchID {
+		t.Fatalf("journal = %+v, want the two batches newest first", batches)
+	}
+
+	// Undoing the older batch leaves the newer one alone; the swap is
+	// reversed as a whole.
+	result, err := a.UndoRename(first.BatchID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Restored != 2 || result.Failed != 0 {
+		t.Errorf("restored %d, failed %d", result.Restored, result.Failed)
+	}
+	checkFiles(t, dir, map[string]string{"a.flac": "a", "b.flac": "b", "03. C.flac": "c"})
+	if _, err := a.UndoRename(first.BatchID); err == nil {
+		t.Error("a batch was undone twice")
+	}
+
+	if _, err := a.UndoLastRename(); err != nil {
+		t.Fatal(err)
+	}
+	checkFiles(t, dir, map[string]string{"c.flac": "c", "03. C.flac": ""})
+	if _, err := a.UndoLastRename(); err == nil {
+		t.Error("expected nothing left to undo")
+	}
+	if _, err := a.UndoRename("missing"); err == nil {
+		t.Error("an unknown batch was undone")
+	}
+}
+
+func TestUndoRenameFileMovedAway(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{"a.flac": "a"})
+	a := &App{}
+	renameForUndo(t, a, dir, RenameOptions{}, "a.flac", "01. A.flac")
+
+	elsewhere := filepath.Join(t.TempDir(), "01. A.flac")
+	if err := os.Rename(filepath.Join(dir, "01. A.flac"), elsewhere); err != nil {
+		t.Fatal(err)
+	}
+	result, err := a.UndoLastRename()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Failed != 1 || result.Entries[0].Reason != "file is no longer at its renamed location" {
+		t.Errorf("result = %+v", result)
+	}
+
+	// The batch stays open, so the undo can be retried once the file is back.
+	if err := os.Rename(elsewhere, filepath.Join(dir, "01. A.flac")); err != nil {
+		t.Fatal(err)
+	}
+	if result, err = a.UndoLastRename(); err != nil || result.Restored != 1 {
+		t.Fatalf("retry: %+v, %v", result, err)
+	}
+	checkFiles(t, dir, map[string]string{"a.flac": "a", "01. A.flac": ""})
+}
+
+func TestUndoRenameReplacedIdentical(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{"a.flac": "same", "01. A.flac": "same"})
+	a := &App{}
+	report := renameForUndo(t, a, dir, RenameOptions{CollisionPolicy: collisionIdentical}, "a.flac", "01. A.flac")
+	if report.Results[0].Collision != "replaced-identical" {
+		t.Fatalf("collision = %q", report.Results[0].Collision)
+	}
+	checkFiles(t, dir, map[string]string{"a.flac": "", "01. A.flac": "same"})
+
+	// The copy that was already there before the rename stays.
+	result, err := a.UndoLastRename()
+	if err != nil || result.Restored != 1 {
+		t.Fatalf("undo: %+v, %v", result, err)
+	}
+	checkFiles(t, dir, map[string]string{"a.flac": "same", "01. A.flac": "same"})
+}
+
+func TestUndoRenameQuarantine(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{"a.flac": "new", "01. A.flac": "old"})
+	a := &App{}
+	renameForUndo(t, a, dir, RenameOptions{CollisionPolicy: collisionQuarantine}, "a.flac", "01. A.flac")
+	quarantined := filepath.Join("_quarantine", "01. A.flac")
+	checkFiles(t, dir, map[string]string{"a.flac": "", "01. A.flac": "new", quarantined: "old"})
+
+	result, err := a.UndoLastRename()
+	if err != nil || result.Restored != 2 {
+		t.Fatalf("undo: %+v, %v", result, err)
+	}
+	checkFiles(t, dir, map[string]string{"a.flac": "new", "01. A.flac": "old", quarantined: ""})
+	if _, err := os.Stat(filepath.Join(dir, "_quarantine")); !os.IsNotExist(err) {
+		t.Error("empty quarantine folder was left behind")
+	}
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	neturl "net/url"
+	"regexp"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+// Juno Download release pages live at /products/<slug>/<id>/. They mark up
+// the release with schema.org microdata; JSON-LD is used when present.
+
+// Catalog numbers are one word, or a prefix and a number: "CAT001", "JL 012".
+// The second word must start with a digit so the next label on the page
+// ("Released: ...") is not taken for part of it.
+var reCatalogNumber = regexp.MustCompile(`(?i)\bcat(?:alog)?(?:ue)?\.?\s*(?:no\.?|number|#)?\s*:\s*([A-Za-z0-9][A-Za-z0-9._/-]*(?: [0-9][A-Za-z0-9._/-]*)?)`)
+
+type junoProvider struct{}
+
+func (junoProvider) Name() string { return "Juno Download" }
+
+func (junoProvider) CanHandle(url string) bool {
+	return hostIs(urlHost(url), "junodownload.com")
+}
+
+func (p junoProvider) Fetch(ctx context.Context, url string) (*AlbumData, error) {
+	doc, err := fetchDocument(ctx, url)
+	if err != nil {
+		return nil, err
+	}
+	return p.parse(doc)
+}
+
+func (p junoProvider) parse(doc *goquery.Document) (*AlbumData, error) {
+	album := &AlbumData{Source: p.Name()}
+	album.Title = cleanText(doc.Find(".product-title, [itemprop='name']").First().Text())
+	album.Artist = joinLinkTexts(doc.Find(".product-artist a"))
+	album.Label = cleanText(doc.Find(".product-label a, [itemprop='recordLabel'] [itemprop='name']").First().Text())
+	if m := reCatalogNumber.FindStringSubmatch(cleanText(doc.Find(".product-meta, #product-page-digi").Text())); m != nil {
+		album.CatalogNumber = strings.TrimRight(m[1], ".")
+	}
+
+	// Track rows may wrap the microdata element, so it is only used on its
+	// own when there are no rows.
+	rows := doc.Find(".product-tracklist-track")
+	if rows.Length() == 0 {
+		rows = doc.Find("[itemprop='track']")
+	}
+	rows.Each(func(i int, row *goquery.Selection) {
+		title := cleanText(row.Find("[itemprop='name']").First().AttrOr("content", ""))
+		if title == "" {
+			title = cleanText(row.Find("[itemprop='name']").First().Text())
+		}
+		if title == "" {
+			return
+		}
+		// Juno titles read "Artist - Title (Mix)" on various-artist releases.
+		artist := joinLinkTexts(row.Find("[itemprop='byArtist'] a, .col-artist a"))
+		if artist == "" {
+			if parts := strings.SplitN(title, " - ", 2); len(parts) == 2 {
+				artist, title = strings.TrimSpace(parts[0]), strings.TrimSpace(parts[1])
+			}
+		}
+		duration := row.Find("[itemprop='duration']").First()
+		album.Tracks = append(album.Tracks, AlbumTrack{
+			Title:            title,
+			Artist:           artist,
+			TrackNum:         len(album.Tracks) + 1,
+			TrackNumExplicit: true,
+			Duration:         parseDurationString(firstNonEmpty(duration.AttrOr("content", ""), duration.AttrOr("datetime", ""), cleanText(duration.Text()))),
+		})
+	})
+
+	if len(album.Tracks) == 0 {
+		tracks, title, artist := parseJSONLDRelease(doc)
+		if len(tracks) == 0 {
+			return nil, fmt.Errorf("could not find Juno Download track data on page")
+		}
+		album.Tracks = tracks
+		album.Title = firstNonEmpty(album.Title, title)
+		album.Artist = firstNonEmpty(album.Artist, artist)
+	}
+	return album, nil
+}
+
+func (p junoProvider) Search(ctx context.Context, query string) ([]ReleaseSummary, error) {
+	doc, err := fetchDocument(ctx, "https://www.junodownload.com/search/?solrorder=relevancy&q%5Ball%5D%5B%5D="+neturl.QueryEscape(query))
+	if err != nil {
+		return nil, err
+	}
+	return releaseLinks(doc, p.Name(), "https://www.junodownload.com", "/products/"), nil
+}
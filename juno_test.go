@@ -0,0 +1,43 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestJunoParse(t *testing.T) {
+	album, err := junoProvider{}.parse(loadHTMLFixture(t, "juno/release.html"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if album.Title != "Deep Water Sampler Vol 2" || album.Artist != "VARIOUS" || album.Label != "Soma" {
+		t.Errorf("album = %q by %q on %q", album.Title, album.Artist, album.Label)
+	}
+	if album.CatalogNumber != "SOMA 123" {
+		t.Errorf("catalog number = %q, want SOMA 123", album.CatalogNumber)
+	}
+	// Each row wraps its microdata element; neither may add a second track.
+	checkTracks(t, album, []AlbumTrack{
+		{Title: "Sonar (original mix)", Artist: "Deepline", TrackNum: 1, Duration: 465},
+		{Title: "Ballast", Artist: "Hull & Keel", TrackNum: 2, Duration: 362},
+		{Title: "Undertow", Artist: "Harbour", TrackNum: 3, Duration: 481},
+	})
+}
+
+func TestCatalogNumber(t *testing.T) {
+	for text, want := range map[string]string{
+		"Cat: SOMA123 Released: 12 Jan 24": "SOMA123",
+		"Cat: JL 012 Released: 12 Jan 24":  "JL 012",
+		"Catalogue No.: CAT-001.":          "CAT-001",
+		"Cat. #: ABC/12 Genre: House":      "ABC/12",
+		"Category: Techno":                 "",
+	} {
+		got := ""
+		if m := reCatalogNumber.FindStringSubmatch(text); m != nil {
+			got = strings.TrimRight(m[1], ".")
+		}
+		if got != want {
+			t.Errorf("%q: got %q, want %q", text, got, want)
+		}
+	}
+}
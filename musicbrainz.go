@@ -0,0 +1,187 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	neturl "net/url"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// MusicBrainz is read through its JSON web service. Release pages
+// (musicbrainz.org/release/<mbid>) and bare release MBIDs are accepted.
+
+var reMBID = regexp.MustCompile(`(?i)[0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12}`)
+
+type musicBrainzProvider struct{}
+
+type mbArtistCredit struct {
+	Name       string `json:"name"`
+	JoinPhrase string `json:"joinphrase"`
+}
+
+type mbTrack struct {
+	Position     int              `json:"position"`
+	Number       string           `json:"number"`
+	Title        string           `json:"title"`
+	Length       int              `json:"length"`
+	ArtistCredit []mbArtistCredit `json:"artist-credit"`
+	Recording    struct {
+		Length int      `json:"length"`
+		ISRCs  []string `json:"isrcs"`
+	} `json:"recording"`
+}
+
+type mbRelease struct {
+	ID           string           `json:"id"`
+	Title        string           `json:"title"`
+	Date         string           `json:"date"`
+	ArtistCredit []mbArtistCredit `json:"artist-credit"`
+	LabelInfo    []struct {
+		CatalogNumber string `json:"catalog-number"`
+		Label         *struct {
+			Name string `json:"name"`
+		} `json:"label"`
+	} `json:"label-info"`
+	TrackCount int `json:"track-count"`
+	Media      []struct {
+		Position int       `json:"position"`
+		Tracks   []mbTrack `json:"tracks"`
+	} `json:"media"`
+}
+
+func (musicBrainzProvider) Name() string { return "MusicBrainz" }
+
+func (musicBrainzProvider) CanHandle(url string) bool {
+	url = strings.TrimSpace(url)
+	if reMBID.MatchString(url) && len(url) == 36 {
+		return true
+	}
+	return hostIs(urlHost(url), "musicbrainz.org") && strings.Contains(url, "/release/") && reMBID.MatchString(url)
+}
+
+func (p musicBrainzProvider) Fetch(ctx context.Context, url string) (*AlbumData, error) {
+	mbid := reMBID.FindString(url)
+	if mbid == "" {
+		return nil, fmt.Errorf("could not find a MusicBrainz release ID in %s", url)
+	}
+	var release mbRelease
+	api := "https://musicbrainz.org/ws/2/release/" + strings.ToLower(mbid) + "?inc=recordings+artist-credits+isrcs+labels&fmt=json"
+	if err := p.getJSON(ctx, api, &release); err != nil {
+		return nil, err
+	}
+	return p.parse(&release)
+}
+
+func (p musicBrainzProvider) getJSON(ctx context.Context, url string, out interface{}) error {
+	res, err := getWithUserAgent(ctx, url)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	if res.StatusCode != 200 {
+		return fmt.Errorf("status code error: %d %s", res.StatusCode, res.Status)
+	}
+	data, err := io.ReadAll(res.Body)
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(data, out); err != nil {
+		return fmt.Errorf("failed to unmarshal MusicBrainz response: %w", err)
+	}
+	return nil
+}
+
+// parse keeps the disc structure: each track carries its medium position and
+// its position on that medium, so a two-CD set is named 1-01 … 2-01 ….
+func (p musicBrainzProvider) parse(release *mbRelease) (*AlbumData, error) {
+	album := &AlbumData{
+		Artist:      mbCreditString(release.ArtistCredit),
+		Title:       strings.TrimSpace(release.Title),
+		ReleaseDate: release.Date,
+		Source:      p.Name(),
+	}
+	if len(release.Date) >= 4 {
+		album.Year, _ = strconv.Atoi(release.Date[:4])
+	}
+	for _, info := range release.LabelInfo {
+		if album.Label == "" && info.Label != nil {
+			album.Label = strings.TrimSpace(info.Label.Name)
+		}
+		if album.CatalogNumber == "" && !strings.EqualFold(info.CatalogNumber, "[none]") {
+			album.CatalogNumber = strings.TrimSpace(info.CatalogNumber)
+		}
+	}
+	album.DiscTotal = len(release.Media)
+	for i, medium := range release.Media {
+		disc := medium.Position
+		if disc == 0 {
+			disc = i + 1
+		}
+		for _, t := range medium.Tracks {
+			length := t.Length
+			if length == 0 {
+				length = t.Recording.Length
+			}
+			track := AlbumTrack{
+				Title:            strings.TrimSpace(t.Title),
+				Artist:           mbCreditString(t.ArtistCredit),
+				TrackNum:         t.Position,
+				TrackNumExplicit: t.Position > 0,
+				DiscNum:          disc,
+				Duration:         float64(length) / 1000,
+			}
+			// Vinyl media number tracks A1, B1; keep that as the position.
+			if _, err := strconv.Atoi(t.Number); err != nil {
+				track.Position = t.Number
+			}
+			if len(t.Recording.ISRCs) > 0 {
+				track.ISRC = t.Recording.ISRCs[0]
+			}
+			album.Tracks = append(album.Tracks, track)
+		}
+	}
+	if len(album.Tracks) == 0 {
+		return nil, fmt.Errorf("MusicBrainz release has no tracks")
+	}
+	return album, nil
+}
+
+// mbCreditString joins an artist credit with its join phrases, exactly as
+// MusicBrainz displays it ("A feat. B & C").
+func mbCreditString(credits []mbArtistCredit) string {
+	var b strings.Builder
+	for _, c := range credits {
+		b.WriteString(c.Name)
+		b.WriteString(c.JoinPhrase)
+	}
+	return strings.TrimSpace(b.String())
+}
+
+func (p musicBrainzProvider) Search(ctx context.Context, query string) ([]ReleaseSummary, error) {
+	var page struct {
+		Releases []mbRelease `json:"releases"`
+	}
+	url := "https://musicbrainz.org/ws/2/release?fmt=json&limit=10&query=" + neturl.QueryEscape(query)
+	if err := p.getJSON(ctx, url, &page); err != nil {
+		return nil, err
+	}
+	results := make([]ReleaseSummary, 0, len(page.Releases))
+	for _, r := range page.Releases {
+		summary := ReleaseSummary{
+			Provider:   p.Name(),
+			URL:        "https://musicbrainz.org/release/" + r.ID,
+			Artist:     mbCreditString(r.ArtistCredit),
+			Title:      r.Title,
+			TrackCount: r.TrackCount,
+		}
+		if len(r.LabelInfo) > 0 && r.LabelInfo[0].Label != nil {
+			summary.Label = r.LabelInfo[0].Label.Name
+		}
+		results = append(results, summary)
+	}
+	return results, nil
+}
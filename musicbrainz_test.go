@@ -0,0 +1,65 @@
+package main
+
+import "testing"
+
+func TestMusicBrainzParseMultiDisc(t *testing.T) {
+	var release mbRelease
+	loadJSONFixture(t, "musicbrainz/release-2cd.json", &release)
+	album, err := musicBrainzProvider{}.parse(&release)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if album.Artist != "Kora & The Lanterns" || album.Title != "Night Drive" || album.Year != 2019 {
+		t.Errorf("album = %q / %q / %d", album.Artist, album.Title, album.Year)
+	}
+	// The first label-info has no label and a "[none]" catalog number.
+	if album.Label != "Nite Records" || album.CatalogNumber != "NITE 012" {
+		t.Errorf("label = %q %q, want Nite Records NITE 012", album.Label, album.CatalogNumber)
+	}
+	if album.DiscTotal != 2 {
+		t.Errorf("DiscTotal = %d, want 2", album.DiscTotal)
+	}
+	checkTracks(t, album, []AlbumTrack{
+		{Title: "Tunnel Lights", Artist: "Kora feat. Mel", TrackNum: 1, DiscNum: 1, Duration: 241, ISRC: "GBXYZ1900001"},
+		{Title: "Overpass", Artist: "The Lanterns", TrackNum: 2, DiscNum: 1, Duration: 305},
+		{Title: "Exit 9 (Dawn Version)", Artist: "Kora vs. Mel & Dax", TrackNum: 1, DiscNum: 2, Duration: 412, ISRC: "GBXYZ1900003"},
+	})
+}
+
+func TestMusicBrainzParseVinyl(t *testing.T) {
+	var release mbRelease
+	loadJSONFixture(t, "musicbrainz/release-vinyl.json", &release)
+	album, err := musicBrainzProvider{}.parse(&release)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if album.Label != "Self-Released" || album.CatalogNumber != "" {
+		t.Errorf("label = %q %q, want Self-Released and no catalog number", album.Label, album.CatalogNumber)
+	}
+	if album.Year != 1998 || album.DiscTotal != 1 {
+		t.Errorf("year %d, discs %d", album.Year, album.DiscTotal)
+	}
+	checkTracks(t, album, []AlbumTrack{
+		{Title: "Low Tide", Artist: "Harbour", TrackNum: 1, DiscNum: 1, Position: "A1", Duration: 380},
+		{Title: "Undertow", Artist: "Harbour", TrackNum: 2, DiscNum: 1, Position: "A2", Duration: 355},
+		{Title: "Breakwater", Artist: "Harbour", TrackNum: 3, DiscNum: 1, Position: "B1", Duration: 402},
+	})
+}
+
+func TestMBCreditString(t *testing.T) {
+	for _, tc := range []struct {
+		credits []mbArtistCredit
+		want    string
+	}{
+		{nil, ""},
+		{[]mbArtistCredit{{Name: "Solo"}}, "Solo"},
+		{[]mbArtistCredit{{Name: "A", JoinPhrase: " feat. "}, {Name: "B", JoinPhrase: " & "}, {Name: "C"}}, "A feat. B & C"},
+		{[]mbArtistCredit{{Name: "A", JoinPhrase: ", "}, {Name: "B", JoinPhrase: " and "}, {Name: "C", JoinPhrase: " "}}, "A, B and C"},
+	} {
+		if got := mbCreditString(tc.credits); got != tc.want {
+			t.Errorf("mbCreditString(%+v) = %q, want %q", tc.credits, got, tc.want)
+		}
+	}
+}
@@ -0,0 +1,418 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	goruntime "runtime"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// fileMove is one requested rename inside a batch. runMoves fills in Outcome
+// and Reason.
+type fileMove struct {
+	From      string
+	To        string
+	Outcome   string
+	Reason    string
+	Collision string // what the collision policy did, if the target existed
+
+	current    string // where the file is right now (differs from From while parked on a temp name)
+	replace    bool   // target is byte-identical and may be overwritten
+	quarantine bool   // extra move that clears an incumbent out of the way
+}
+
+// Collision policies for targets that already exist on disk.
+const (
+	collisionSkip       = "skip"
+	collisionSuffix     = "suffix"
+	collisionIdentical  = "identical"
+	collisionQuarantine = "quarantine"
+)
+
+// collisionPolicy says what to do when a target already exists and is not
+// part of the batch. QuarantineDir is used by collisionQuarantine; when
+// empty, incumbents go to a "_quarantine" folder next to the target.
+type collisionPolicy struct {
+	Mode          string
+	QuarantineDir string
+}
+
+// runMoves applies a whole batch of renames as a graph rather than one by
+// one, so chains (A->B, B->C), swaps (A<->B) and case-only renames work:
+//
+//   - two moves with the same target are true conflicts and neither runs;
+//   - a target that is some other move's source waits for that move;
+//   - cycles are broken by parking one file on a temporary name;
+//   - a case-only rename on a case-insensitive filesystem goes through a
+//     temporary name too, since the target "exists" as the file itself.
+//
+// Targets that already exist are handled according to policy. Moves are
+// reported in the order they were actually applied, including any extra
+// quarantine moves.
+//
+// Once ctx is cancelled the remaining moves are skipped, but a cycle that was
+// already broken open is finished first, so no file is left on a temporary
+// name.
+func runMoves(ctx context.Context, moves []*fileMove, policy collisionPolicy) []*fileMove {
+	keys := newPathKeyer()
+	pending := make([]*fileMove, 0, len(moves))
+	for _, m := range moves {
+		m.current = m.From
+		if m.From == m.To {
+			m.Outcome = outcomeSkippedUnchanged
+			continue
+		}
+		if _, err := os.Stat(m.From); err != nil {
+			m.Outcome = outcomeError
+			m.Reason = fmt.Sprintf("source file is missing: %v", err)
+			continue
+		}
+		pending = append(pending, m)
+	}
+
+	// Two files proposing the same name can never both succeed.
+	byTarget := make(map[string][]*fileMove)
+	for _, m := range pending {
+		byTarget[keys.key(m.To)] = append(byTarget[keys.key(m.To)], m)
+	}
+	pending = filterMoves(pending, func(m *fileMove) bool {
+		if clash := byTarget[keys.key(m.To)]; len(clash) > 1 {
+			m.Outcome = outcomeError
+			m.Reason = fmt.Sprintf("conflict: %d files would be renamed to %s", len(clash), filepath.Base(m.To))
+			return false
+		}
+		return true
+	})
+
+	sources := make(map[string]*fileMove, len(pending))
+	for _, m := range pending {
+		sources[keys.key(m.From)] = m
+	}
+
+	// A target that already exists is only fine if it is about to move away;
+	// otherwise the collision policy decides.
+	claimed := make(map[string]bool, len(byTarget))
+	for k := range byTarget {
+		claimed[k] = true
+	}
+	var extra []*fileMove
+	pending = filterMoves(pending, func(m *fileMove) bool {
+		if _, err := os.Stat(m.To); os.IsNotExist(err) {
+			return true
+		}
+		if _, ok := sources[keys.key(m.To)]; ok {
+			return true
+		}
+		return resolveCollision(m, policy, keys, claimed, &extra)
+	})
+	for _, q := range extra {
+		q.current = q.From
+		sources[keys.key(q.From)] = q
+	}
+	pending = append(extra, pending...)
+	blocked := make(map[string]*fileMove)
+	for k, m := range sources {
+		if m.Outcome != "" {
+			delete(sources, k)
+			markBlocked(m, keys, blocked)
+		}
+	}
+
+	var applied []*fileMove
+	for len(pending) > 0 {
+		parked := 0
+		for _, m := range pending {
+			if m.current != m.From {
+				parked++
+			}
+		}
+		progressed := false
+		pending = filterMoves(pending, func(m *fileMove) bool {
+			if ctx.Err() != nil && parked == 0 {
+				m.Outcome = outcomeSkippedCancelled
+				m.Reason = "cancelled before the file was renamed"
+				progressed = true
+				return false
+			}
+			targetKey := keys.key(m.To)
+			if blocker, ok := blocked[targetKey]; ok {
+				m.Outcome = outcomeError
+				m.Reason = fmt.Sprintf("target is still occupied by %s, which could not be moved", filepath.Base(blocker.From))
+				markBlocked(m, keys, blocked)
+				progressed = true
+				return false
+			}
+			if owner, ok := sources[targetKey]; ok && owner != m {
+				return true
+			}
+			progressed = true
+			delete(sources, keys.key(m.current))
+			if err := applyMove(m, keys); err != nil {
+				m.Outcome = outcomeError
+				m.Reason = err.Error()
+				markBlocked(m, keys, blocked)
+				return false
+			}
+			m.Outcome = outcomeRenamed
+			applied = append(applied, m)
+			return false
+		})
+		if progressed || len(pending) == 0 || ctx.Err() != nil && parked == 0 {
+			continue
+		}
+
+		// Every remaining move waits on another one: there is a cycle.
+		// Park the file blocking the first move on a temporary name.
+		m := sources[keys.key(pending[0].To)]
+		tmp := tempMovePath(m.current)
+		if err := moveFile(m.current, tmp); err != nil {
+			m.Outcome = outcomeError
+			m.Reason = fmt.Sprintf("could not move to a temporary name: %v", err)
+			delete(sources, keys.key(m.current))
+			markBlocked(m, keys, blocked)
+			pending = filterMoves(pending, func(p *fileMove) bool { return p != m })
+			continue
+		}
+		delete(sources, keys.key(m.current))
+		m.current = tmp
+		sources[keys.key(tmp)] = m
+	}
+	return applied
+}
+
+// resolveCollision applies the policy to a move whose target is occupied by
+// a file outside the batch. It returns false when the move is skipped.
+func resolveCollision(m *fileMove, policy collisionPolicy, keys *pathKeyer, claimed map[string]bool, extra *[]*fileMove) bool {
+	switch policy.Mode {
+	case collisionSuffix:
+		delete(claimed, keys.key(m.To))
+		m.To = suffixedPath(m.To, keys, claimed)
+		claimed[keys.key(m.To)] = true
+		m.Collision = "suffixed"
+		return true
+	case collisionIdentical:
+		same, err := filesIdentical(m.From, m.To)
+		if err != nil {
+			m.Outcome = outcomeError
+			m.Reason = fmt.Sprintf("could not compare with existing file: %v", err)
+			return false
+		}
+		if same {
+			m.replace = true
+			m.Collision = "replaced-identical"
+			return true
+		}
+		m.Outcome = outcomeSkippedCollision
+		m.Reason = "target file already exists and is different"
+		m.Collision = collisionSkip
+		return false
+	case collisionQuarantine:
+		dir := policy.QuarantineDir
+		if dir == "" {
+			dir = filepath.Join(filepath.Dir(m.To), "_quarantine")
+		}
+		q := &fileMove{From: m.To, To: suffixedPath(filepath.Join(dir, filepath.Base(m.To)), keys, claimed), quarantine: true}
+		claimed[keys.key(q.To)] = true
+		*extra = append(*extra, q)
+		m.Collision = "quarantined"
+		return true
+	}
+	m.Outcome = outcomeSkippedCollision
+	m.Reason = "target file already exists"
+	m.Collision = collisionSkip
+	return false
+}
+
+// suffixedPath returns path, or "name (2).ext", "name (3).ext"... for the
+// first variant that neither exists nor is claimed by another move.
+func suffixedPath(path string, keys *pathKeyer, claimed map[string]bool) string {
+	ext := filepath.Ext(path)
+	stem := strings.TrimSuffix(path, ext)
+	candidate := path
+	for n := 2; ; n++ {
+		if _, err := os.Lstat(candidate); os.IsNotExist(err) && !claimed[keys.key(candidate)] {
+			return candidate
+		}
+		candidate = fmt.Sprintf("%s (%d)%s", stem, n, ext)
+	}
+}
+
+func filesIdentical(a string, b string) (bool, error) {
+	infoA, err := os.Stat(a)
+	if err != nil {
+		return false, err
+	}
+	infoB, err := os.Stat(b)
+	if err != nil {
+		return false, err
+	}
+	if infoA.Size() != infoB.Size() {
+		return false, nil
+	}
+	fa, err := os.Open(a)
+	if err != nil {
+		return false, err
+	}
+	defer fa.Close()
+	fb, err := os.Open(b)
+	if err != nil {
+		return false, err
+	}
+	defer fb.Close()
+
+	bufA := make([]byte, 64*1024)
+	bufB := make([]byte, 64*1024)
+	for {
+		na, errA := io.ReadFull(fa, bufA)
+		nb, errB := io.ReadFull(fb, bufB)
+		if na != nb || !bytes.Equal(bufA[:na], bufB[:nb]) {
+			return false, nil
+		}
+		if errA == io.EOF || errA == io.ErrUnexpectedEOF {
+			return errB == io.EOF || errB == io.ErrUnexpectedEOF, nil
+		}
+		if errA != nil {
+			return false, errA
+		}
+		if errB != nil {
+			return false, errB
+		}
+	}
+}
+
+// applyMove moves m from wherever it currently is to its target. Case-only
+// renames on case-insensitive filesystems go through a temporary name. A
+// file parked on a temporary name is put back if the final move fails.
+func applyMove(m *fileMove, keys *pathKeyer) error {
+	if err := os.MkdirAll(filepath.Dir(m.To), 0o755); err != nil {
+		return restoreParked(m, fmt.Errorf("could not create folder: %w", err))
+	}
+	if m.replace {
+		// The incumbent is byte-identical, so dropping it loses nothing.
+		if err := os.Remove(m.To); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	if keys.key(m.current) == keys.key(m.To) {
+		tmp := tempMovePath(m.current)
+		if err := moveFile(m.current, tmp); err != nil {
+			return err
+		}
+		m.current = tmp
+	}
+	if err := moveFile(m.current, m.To); err != nil {
+		return restoreParked(m, err)
+	}
+	m.current = m.To
+	return nil
+}
+
+func restoreParked(m *fileMove, cause error) error {
+	if m.current == m.From {
+		return cause
+	}
+	if _, err := os.Lstat(m.From); err == nil {
+		return fmt.Errorf("%v; file was left at %s", cause, m.current)
+	}
+	if err := moveFile(m.current, m.From); err != nil {
+		return fmt.Errorf("%v; file was left at %s", cause, m.current)
+	}
+	m.current = m.From
+	return cause
+}
+
+// markBlocked records that m's original location stays occupied, so moves
+// targeting it fail instead of waiting forever.
+func markBlocked(m *fileMove, keys *pathKeyer, blocked map[string]*fileMove) {
+	if _, err := os.Stat(m.From); err == nil {
+		blocked[keys.key(m.From)] = m
+	}
+}
+
+func filterMoves(moves []*fileMove, keep func(*fileMove) bool) []*fileMove {
+	out := moves[:0]
+	for _, m := range moves {
+		if keep(m) {
+			out = append(out, m)
+		}
+	}
+	return out
+}
+
+func tempMovePath(path string) string {
+	dir := filepath.Dir(path)
+	stamp := strconv.FormatInt(time.Now().UnixNano(), 36)
+	for i := 0; ; i++ {
+		candidate := filepath.Join(dir, fmt.Sprintf(".audiorenamer-%s-%d%s", stamp, i, filepath.Ext(path)))
+		if _, err := os.Lstat(candidate); os.IsNotExist(err) {
+			return candidate
+		}
+	}
+}
+
+// pathKeyer maps paths to comparison keys, folding case for directories on
+// case-insensitive filesystems so "Track.flac" and "track.flac" are
+// recognised as the same file there.
+type pathKeyer struct {
+	insensitive map[string]bool
+}
+
+func newPathKeyer() *pathKeyer {
+	return &pathKeyer{insensitive: make(map[string]bool)}
+}
+
+func (k *pathKeyer) key(path string) string {
+	path = filepath.Clean(path)
+	if k.caseInsensitive(filepath.Dir(path)) {
+		return strings.ToLower(path)
+	}
+	return path
+}
+
+func (k *pathKeyer) caseInsensitive(dir string) bool {
+	if v, ok := k.insensitive[dir]; ok {
+		return v
+	}
+	v := probeCaseInsensitive(dir)
+	k.insensitive[dir] = v
+	return v
+}
+
+// probeCaseInsensitive checks the nearest existing ancestor of dir by
+// looking it up under a case-swapped name. Names without letters fall back
+// to the platform default.
+func probeCaseInsensitive(dir string) bool {
+	for {
+		info, err := os.Stat(dir)
+		if err == nil {
+			base := filepath.Base(dir)
+			swapped := swapCase(base)
+			if swapped != base {
+				other, err := os.Stat(filepath.Join(filepath.Dir(dir), swapped))
+				return err == nil && os.SameFile(info, other)
+			}
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			break
+		}
+		dir = parent
+	}
+	return goruntime.GOOS == "windows" || goruntime.GOOS == "darwin"
+}
+
+func swapCase(s string) string {
+	return strings.Map(func(r rune) rune {
+		if unicode.IsUpper(r) {
+			return unicode.ToLower(r)
+		}
+		return unicode.ToUpper(r)
+	}, s)
+}
@@ -0,0 +1,161 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// writeFiles creates files named after their content in dir.
+func writeFiles(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// checkFiles asserts the content of files in dir; "" means the file must not
+// exist.
+func checkFiles(t *testing.T, dir string, want map[string]string) {
+	t.Helper()
+	for name, content := range want {
+		data, err := os.ReadFile(filepath.Join(dir, name))
+		switch {
+		case content == "" && !os.IsNotExist(err):
+			t.Errorf("%s still exists", name)
+		case content != "" && err != nil:
+			t.Errorf("%s: %v", name, err)
+		case content != "" && string(data) != content:
+			t.Errorf("%s = %q, want %q", name, data, content)
+		}
+	}
+}
+
+// batch builds moves between names in dir.
+func batch(dir string, pairs ...string) []*fileMove {
+	var moves []*fileMove
+	for i := 0; i+1 < len(pairs); i += 2 {
+		moves = append(moves, &fileMove{From: filepath.Join(dir, pairs[i]), To: filepath.Join(dir, pairs[i+1])})
+	}
+	return moves
+}
+
+func TestRunMovesGraph(t *testing.T) {
+	for _, tc := range []struct {
+		name   string
+		files  map[string]string
+		pairs  []string
+		result map[string]string
+	}{
+		{
+			name:   "chain",
+			files:  map[string]string{"a.mp3": "a", "b.mp3": "b"},
+			pairs:  []string{"a.mp3", "b.mp3", "b.mp3", "c.mp3"},
+			result: map[string]string{"a.mp3": "", "b.mp3": "a", "c.mp3": "b"},
+		},
+		{
+			name:   "swap",
+			files:  map[string]string{"a.mp3": "a", "b.mp3": "b"},
+			pairs:  []string{"a.mp3", "b.mp3", "b.mp3", "a.mp3"},
+			result: map[string]string{"a.mp3": "b", "b.mp3": "a"},
+		},
+		{
+			name:   "cycle",
+			files:  map[string]string{"a.mp3": "a", "b.mp3": "b", "c.mp3": "c"},
+			pairs:  []string{"a.mp3", "b.mp3", "b.mp3", "c.mp3", "c.mp3", "a.mp3"},
+			result: map[string]string{"a.mp3": "c", "b.mp3": "a", "c.mp3": "b"},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			dir := t.TempDir()
+			writeFiles(t, dir, tc.files)
+			moves := batch(dir, tc.pairs...)
+			applied := runMoves(context.Background(), moves, collisionPolicy{Mode: collisionSkip})
+			if len(applied) != len(moves) {
+				t.Errorf("applied %d of %d moves", len(applied), len(moves))
+			}
+			for _, m := range moves {
+				if m.Outcome != outcomeRenamed {
+					t.Errorf("%s: outcome %q (%s)", filepath.Base(m.From), m.Outcome, m.Reason)
+				}
+			}
+			checkFiles(t, dir, tc.result)
+			// No file may be left on a temporary name.
+			entries, _ := os.ReadDir(dir)
+			if len(entries) != len(tc.files) {
+				t.Errorf("%d files in folder, want %d", len(entries), len(tc.files))
+			}
+		})
+	}
+}
+
+func TestRunMovesConflicts(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{"a.mp3": "a", "b.mp3": "b", "c.mp3": "c"})
+	moves := batch(dir, "a.mp3", "x.mp3", "b.mp3", "x.mp3", "c.mp3", "c.mp3", "gone.mp3", "y.mp3")
+	if applied := runMoves(context.Background(), moves, collisionPolicy{Mode: collisionSkip}); len(applied) != 0 {
+		t.Errorf("applied %d moves", len(applied))
+	}
+	for i, want := range []string{outcomeError, outcomeError, outcomeSkippedUnchanged, outcomeError} {
+		if moves[i].Outcome != want {
+			t.Errorf("move %d: outcome %q, want %q", i, moves[i].Outcome, want)
+		}
+	}
+	if !strings.HasPrefix(moves[0].Reason, "conflict:") {
+		t.Errorf("reason = %q", moves[0].Reason)
+	}
+	checkFiles(t, dir, map[string]string{"a.mp3": "a", "b.mp3": "b", "x.mp3": ""})
+}
+
+func TestRunMovesCollisionPolicies(t *testing.T) {
+	for _, tc := range []struct {
+		mode      string
+		incumbent string
+		outcome   string
+		collision string
+		result    map[string]string
+	}{
+		{collisionSkip, "other", outcomeSkippedCollision, collisionSkip,
+			map[string]string{"a.mp3": "new", "b.mp3": "other"}},
+		{collisionSuffix, "other", outcomeRenamed, "suffixed",
+			map[string]string{"a.mp3": "", "b.mp3": "other", "b (2).mp3": "new"}},
+		{collisionIdentical, "other", outcomeSkippedCollision, collisionSkip,
+			map[string]string{"a.mp3": "new", "b.mp3": "other"}},
+		{collisionIdentical, "new", outcomeRenamed, "replaced-identical",
+			map[string]string{"a.mp3": "", "b.mp3": "new"}},
+		{collisionQuarantine, "other", outcomeRenamed, "quarantined",
+			map[string]string{"a.mp3": "", "b.mp3": "new", filepath.Join("_quarantine", "b.mp3"): "other"}},
+	} {
+		t.Run(tc.mode+"/"+tc.incumbent, func(t *testing.T) {
+			dir := t.TempDir()
+			writeFiles(t, dir, map[string]string{"a.mp3": "new", "b.mp3": tc.incumbent})
+			moves := batch(dir, "a.mp3", "b.mp3")
+			runMoves(context.Background(), moves, collisionPolicy{Mode: tc.mode})
+			if m := moves[0]; m.Outcome != tc.outcome || m.Collision != tc.collision {
+				t.Errorf("outcome %q, collision %q (%s)", m.Outcome, m.Collision, m.Reason)
+			}
+			checkFiles(t, dir, tc.result)
+		})
+	}
+}
+
+func TestRunMovesCancelled(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{"a.mp3": "a", "b.mp3": "b"})
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	moves := batch(dir, "a.mp3", "x.mp3", "b.mp3", "y.mp3")
+	if applied := runMoves(ctx, moves, collisionPolicy{Mode: collisionSkip}); len(applied) != 0 {
+		t.Errorf("applied %d moves after cancellation", len(applied))
+	}
+	for _, m := range moves {
+		if m.Outcome != outcomeSkippedCancelled {
+			t.Errorf("%s: outcome %q", filepath.Base(m.From), m.Outcome)
+		}
+	}
+	checkFiles(t, dir, map[string]string{"a.mp3": "a", "b.mp3": "b"})
+}
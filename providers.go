@@ -0,0 +1,294 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	neturl "net/url"
+	"strconv"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+// MetadataProvider is a store or database that release metadata can be
+// fetched from. Providers are registered in init and picked by URL, so a new
+// store only needs a provider; the matcher works on AlbumData alone.
+type MetadataProvider interface {
+	// Name is shown as the match status ("<Name> Match").
+	Name() string
+	// CanHandle reports whether url is a release page of this provider.
+	CanHandle(url string) bool
+	// Fetch loads the release at url.
+	Fetch(ctx context.Context, url string) (*AlbumData, error)
+	// Search looks up releases matching a free-text query.
+	Search(ctx context.Context, query string) ([]ReleaseSummary, error)
+}
+
+// ReleaseSummary is one search result: enough to show it and to fetch it.
+type ReleaseSummary struct {
+	Provider   string `json:"provider"`
+	URL        string `json:"url"`
+	Artist     string `json:"artist"`
+	Title      string `json:"title"`
+	Label      string `json:"label"`
+	TrackCount int    `json:"trackCount"`
+	// Score ranks suggestions (SuggestReleases), from 0 to 1.
+	Score float64 `json:"score"`
+}
+
+// DiscographyProvider is implemented by providers whose artist and label
+// pages list releases to choose from.
+type DiscographyProvider interface {
+	// IsDiscography reports whether url is such a page rather than a release.
+	IsDiscography(url string) bool
+	// Discography lists the releases on the page at url.
+	Discography(ctx context.Context, url string) ([]ReleaseSummary, error)
+}
+
+var metadataProviders []MetadataProvider
+
+func init() {
+	registerMetadataProvider(beatportProvider{})
+	registerMetadataProvider(newDiscogsProvider())
+	registerMetadataProvider(musicBrainzProvider{})
+	registerMetadataProvider(traxsourceProvider{})
+	registerMetadataProvider(junoProvider{})
+	// Bandcamp also serves artists on their own domains, so it accepts any
+	// album URL and must stay last.
+	registerMetadataProvider(bandcampProvider{})
+}
+
+// registerMetadataProvider adds a provider. URLs are offered to providers in
+// registration order, so more specific ones must be registered first.
+func registerMetadataProvider(p MetadataProvider) {
+	metadataProviders = append(metadataProviders, p)
+}
+
+func providerForURL(url string) (MetadataProvider, error) {
+	for _, p := range metadataProviders {
+		if p.CanHandle(url) {
+			return p, nil
+		}
+	}
+	return nil, fmt.Errorf("no metadata source recognises %s", url)
+}
+
+func (a *App) fetchAlbumData(ctx context.Context, url string) (*AlbumData, error) {
+	provider, err := providerForURL(strings.TrimSpace(url))
+	if err != nil {
+		return nil, err
+	}
+	return provider.Fetch(ctx, strings.TrimSpace(url))
+}
+
+// context returns the app context, or a background context before startup.
+func (a *App) context() context.Context {
+	if a.ctx == nil {
+		return context.Background()
+	}
+	return a.ctx
+}
+
+// resolveURL resolves a link found on pageURL, which may be relative.
+func resolveURL(pageURL string, href string) string {
+	base, err := neturl.Parse(strings.TrimSpace(pageURL))
+	if err != nil {
+		return href
+	}
+	ref, err := neturl.Parse(strings.TrimSpace(href))
+	if err != nil {
+		return href
+	}
+	return base.ResolveReference(ref).String()
+}
+
+// urlHost returns the lower-cased host of url, or "" if it does not parse.
+func urlHost(url string) string {
+	u, err := neturl.Parse(strings.TrimSpace(url))
+	if err != nil {
+		return ""
+	}
+	return strings.ToLower(u.Hostname())
+}
+
+// hostIs reports whether host is domain or one of its subdomains, so
+// "notbandcamp.com" is not taken for Bandcamp.
+func hostIs(host string, domain string) bool {
+	return host == domain || strings.HasSuffix(host, "."+domain)
+}
+
+// getWithUserAgent fetches url with the shared client (which sets the
+// configured user agent) through the on-disk cache (see cachedGet).
+func getWithUserAgent(ctx context.Context, url string) (*http.Response, error) {
+	return getWithHeaders(ctx, url, nil)
+}
+
+// getWithHeaders is getWithUserAgent with extra request headers, for
+// credentials that must stay out of the URL.
+func getWithHeaders(ctx context.Context, url string, header http.Header) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return nil, err
+	}
+	for k, v := range header {
+		req.Header[k] = v
+	}
+	return cachedGet(ctx, req, sharedHTTPClient().Do)
+}
+
+// fetchDocument loads an HTML page for scraping.
+func fetchDocument(ctx context.Context, url string) (*goquery.Document, error) {
+	res, err := getWithUserAgent(ctx, url)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != 200 {
+		return nil, fmt.Errorf("status code error: %d %s", res.StatusCode, res.Status)
+	}
+	return goquery.NewDocumentFromReader(res.Body)
+}
+
+// --- Scraping helpers shared by the providers ---
+
+func cleanText(s string) string {
+	return strings.TrimSpace(reSpaces.ReplaceAllString(s, " "))
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if strings.TrimSpace(v) != "" {
+			return strings.TrimSpace(v)
+		}
+	}
+	return ""
+}
+
+// joinLinkTexts joins the text of each selected element, e.g. the artist
+// links of a track row.
+func joinLinkTexts(sel *goquery.Selection) string {
+	var names []string
+	sel.Each(func(_ int, s *goquery.Selection) {
+		if name := cleanText(s.Text()); name != "" {
+			names = append(names, name)
+		}
+	})
+	return strings.Join(names, ", ")
+}
+
+// withMixName appends the mix name in brackets, the way stores display it,
+// unless the title already contains it.
+func withMixName(title string, mix string) string {
+	if mix == "" || strings.Contains(strings.ToLower(title), strings.ToLower(mix)) {
+		return title
+	}
+	return strings.TrimSpace(title) + " (" + mix + ")"
+}
+
+// releaseLinks turns the release links on a search results page into
+// summaries. It is used for stores whose result markup carries little more
+// than a linked title.
+func releaseLinks(doc *goquery.Document, provider string, base string, pathPrefix string) []ReleaseSummary {
+	var results []ReleaseSummary
+	seen := map[string]bool{}
+	doc.Find("a[href*='" + pathPrefix + "']").Each(func(_ int, a *goquery.Selection) {
+		href := a.AttrOr("href", "")
+		title := cleanText(a.Text())
+		if title == "" {
+			return
+		}
+		if strings.HasPrefix(href, "/") {
+			href = base + href
+		}
+		if i := strings.Index(href, "?"); i >= 0 {
+			href = href[:i]
+		}
+		if seen[href] {
+			return
+		}
+		seen[href] = true
+		results = append(results, ReleaseSummary{Provider: provider, URL: href, Title: title})
+	})
+	return results
+}
+
+// --- JSON helpers shared by the providers ---
+
+func getStringFromMap(obj map[string]interface{}, keys ...string) string {
+	for _, key := range keys {
+		if val, ok := obj[key]; ok {
+			if s, ok := val.(string); ok {
+				return strings.TrimSpace(s)
+			}
+		}
+	}
+	return ""
+}
+
+func parseArtistsField(value interface{}) string {
+	switch v := value.(type) {
+	case string:
+		return strings.TrimSpace(v)
+	case map[string]interface{}:
+		return getStringFromMap(v, "name")
+	case []interface{}:
+		names := make([]string, 0, len(v))
+		for _, item := range v {
+			switch t := item.(type) {
+			case string:
+				if strings.TrimSpace(t) != "" {
+					names = append(names, strings.TrimSpace(t))
+				}
+			case map[string]interface{}:
+				if name := getStringFromMap(t, "name"); name != "" {
+					names = append(names, name)
+				}
+			}
+		}
+		return strings.Join(names, ", ")
+	}
+	return ""
+}
+
+func extractTrackNumberFromObj(obj map[string]interface{}) (int, bool) {
+	keys := []string{"trackNumber", "track_number", "position", "number", "index", "trackNo"}
+	explicit := false
+	values := make([]interface{}, 0, len(keys))
+	for _, key := range keys {
+		if val, ok := obj[key]; ok {
+			explicit = true
+			values = append(values, val)
+		}
+	}
+	if !explicit {
+		return 0, false
+	}
+	return parseIntFromAny(values...), true
+}
+
+func parseIntFromAny(values ...interface{}) int {
+	for _, value := range values {
+		switch v := value.(type) {
+		case int:
+			return v
+		case int64:
+			return int(v)
+		case float64:
+			if v > 0 {
+				return int(v)
+			}
+		case json.Number:
+			if n, err := v.Int64(); err == nil {
+				return int(n)
+			}
+		case string:
+			if n, err := strconv.Atoi(strings.TrimSpace(v)); err == nil {
+				return n
+			}
+		}
+	}
+	return 0
+}
@@ -0,0 +1,266 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/wailsapp/wails/v2/pkg/runtime"
+)
+
+// RenameOptions controls where renamed files end up. When LibraryRoot is set,
+// proposed names (which may contain "/" from a folder template) are resolved
+// against it instead of each file's current directory, so files are moved
+// into an organised tree.
+type RenameOptions struct {
+	LibraryRoot      string `json:"libraryRoot"`
+	CleanupEmptyDirs bool   `json:"cleanupEmptyDirs"`
+	SourceRoot       string `json:"sourceRoot"`
+	Method           string `json:"method"`
+	CollisionPolicy  string `json:"collisionPolicy"`
+	QuarantineDir    string `json:"quarantineDir"`
+	FilenameProfile  string `json:"filenameProfile"`
+}
+
+// SelectLibraryRoot asks for the folder that folder templates are applied to.
+func (a *App) SelectLibraryRoot() (string, error) {
+	return runtime.OpenDirectoryDialog(a.ctx, runtime.OpenDialogOptions{
+		Title: "Select Library Root",
+	})
+}
+
+// Per-file outcomes reported by RenameMatchedTracks.
+const (
+	outcomeRenamed          = "renamed"
+	outcomeSkippedUnchanged = "skipped-unchanged"
+	outcomeSkippedCollision = "skipped-collision"
+	outcomeSkippedCancelled = "skipped-cancelled"
+	outcomeError            = "error"
+)
+
+type RenameResult struct {
+	LocalPath       string `json:"localPath"`
+	OriginalName    string `json:"originalName"`
+	ProposedNewName string `json:"proposedNewName"`
+	NewPath         string `json:"newPath"`
+	Outcome         string `json:"outcome"`
+	Reason          string `json:"reason"`
+	Collision       string `json:"collision"`
+}
+
+type RenameReport struct {
+	BatchID      string         `json:"batchId"`
+	Renamed      int            `json:"renamed"`
+	Skipped      int            `json:"skipped"`
+	Failed       int            `json:"failed"`
+	Results      []RenameResult `json:"results"`
+	JournalError string         `json:"journalError"`
+}
+
+func (a *App) RenameMatchedTracks(tracks []MatchedTrack, options RenameOptions) (*RenameReport, error) {
+	profile, err := resolveFilenameProfile(options.FilenameProfile)
+	if err != nil {
+		return nil, err
+	}
+	report := &RenameReport{Results: make([]RenameResult, 0, len(tracks))}
+	moves := make([]*fileMove, len(tracks))
+	var planned []*fileMove
+	for i, track := range tracks {
+		if isUnnamedLeftover(track) {
+			moves[i] = &fileMove{From: track.LocalPath, To: track.LocalPath, Outcome: outcomeSkippedUnchanged, Reason: "no name was found for this file"}
+			continue
+		}
+		// Names edited by hand after the preview are checked again here, so
+		// nothing unsafe for the target filesystem reaches the disk.
+		name, _, err := sanitizeProposedName(track.ProposedNewName, targetBase(track, options), profile)
+		if err != nil {
+			moves[i] = &fileMove{From: track.LocalPath, To: targetPath(track, options), Outcome: outcomeError, Reason: err.Error()}
+			continue
+		}
+		tracks[i].ProposedNewName = name
+		moves[i] = &fileMove{From: track.LocalPath, To: targetPath(tracks[i], options)}
+		planned = append(planned, moves[i])
+	}
+
+	// The batch is planned as a whole so chains and swaps resolve.
+	// Cancelling stops it between files; what was renamed is journaled.
+	ctx, done := a.startJob("rename")
+	defer done()
+	applied := runMoves(ctx, planned, collisionPolicy{
+		Mode:          options.CollisionPolicy,
+		QuarantineDir: strings.TrimSpace(options.QuarantineDir),
+	})
+
+	batch := newRenameBatch(options.Method, strings.TrimSpace(options.LibraryRoot))
+	statusByPath := make(map[string]string, len(tracks))
+	for _, track := range tracks {
+		statusByPath[track.LocalPath] = track.Status
+	}
+	sourceDirs := make(map[string]struct{})
+	for _, m := range applied {
+		method := statusByPath[m.From]
+		if m.quarantine {
+			method = "Quarantine"
+		} else {
+			sourceDirs[filepath.Dir(m.From)] = struct{}{}
+		}
+		batch.Entries = append(batch.Entries, JournalEntry{
+			OldPath:  m.From,
+			NewPath:  m.To,
+			Method:   method,
+			Replaced: m.replace,
+		})
+	}
+
+	for i, track := range tracks {
+		m := moves[i]
+		switch m.Outcome {
+		case outcomeRenamed:
+			report.Renamed++
+		case outcomeError:
+			log.Printf("Error renaming %s to %s: %s", m.From, m.To, m.Reason)
+			report.Failed++
+		default:
+			report.Skipped++
+		}
+		report.Results = append(report.Results, RenameResult{
+			LocalPath:       track.LocalPath,
+			OriginalName:    track.OriginalName,
+			ProposedNewName: track.ProposedNewName,
+			NewPath:         m.To,
+			Outcome:         m.Outcome,
+			Reason:          m.Reason,
+			Collision:       m.Collision,
+		})
+	}
+
+	if options.CleanupEmptyDirs {
+		for dir := range sourceDirs {
+			removeEmptyDirs(dir, options.SourceRoot)
+		}
+	}
+	if len(batch.Entries) > 0 {
+		report.BatchID = batch.ID
+	}
+	if err := a.appendJournal(batch); err != nil {
+		log.Printf("Error writing rename journal: %v", err)
+		report.JournalError = err.Error()
+	}
+	return report, nil
+}
+
+// isUnnamedLeftover reports a file no method found a name for (unmatched,
+// failed to parse, or not processed at all) and whose name nobody has
+// edited. It is listed for information only and must not be moved, not even
+// into the library tree.
+func isUnnamedLeftover(track MatchedTrack) bool {
+	return !isMatchStatus(track.Status) && track.ProposedNewName == track.OriginalName
+}
+
+// isMatchStatus reports the statuses of rows that were given a name: a
+// template or AI result, or "<Source> Match" from a store.
+func isMatchStatus(status string) bool {
+	switch status {
+	case statusMatched, statusAIParsed:
+		return true
+	case statusNoMatch:
+		return false
+	}
+	return strings.HasSuffix(status, " Match")
+}
+
+// targetPath resolves a proposed name to an absolute path. Proposed names
+// always use "/" between folders, whatever the platform.
+func targetPath(track MatchedTrack, options RenameOptions) string {
+	return filepath.Join(targetBase(track, options), filepath.FromSlash(track.ProposedNewName))
+}
+
+func targetBase(track MatchedTrack, options RenameOptions) string {
+	if root := strings.TrimSpace(options.LibraryRoot); root != "" {
+		return root
+	}
+	return filepath.Dir(track.LocalPath)
+}
+
+// moveFile renames src to dst, falling back to copy and delete when the two
+// paths are on different volumes.
+func moveFile(src string, dst string) error {
+	err := os.Rename(src, dst)
+	if err == nil {
+		return nil
+	}
+	var linkErr *os.LinkError
+	if !errors.As(err, &linkErr) {
+		return err
+	}
+	if _, statErr := os.Stat(src); statErr != nil {
+		return err
+	}
+	if copyErr := copyFile(src, dst); copyErr != nil {
+		os.Remove(dst)
+		return fmt.Errorf("%v (copy fallback failed: %w)", err, copyErr)
+	}
+	return os.Remove(src)
+}
+
+func copyFile(src string, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	info, err := in.Stat()
+	if err != nil {
+		return err
+	}
+	out, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_EXCL, info.Mode().Perm())
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	if err := out.Close(); err != nil {
+		return err
+	}
+	return os.Chtimes(dst, info.ModTime(), info.ModTime())
+}
+
+// removeEmptyDirs deletes dir if it is empty, then walks up removing parents
+// that became empty. It never goes above stopAt; without stopAt only dir
+// itself is considered.
+func removeEmptyDirs(dir string, stopAt string) {
+	stopAt = filepath.Clean(stopAt)
+	for {
+		if dir == stopAt {
+			return
+		}
+		entries, err := os.ReadDir(dir)
+		if err != nil || len(entries) > 0 {
+			return
+		}
+		if err := os.Remove(dir); err != nil {
+			log.Printf("Could not remove empty folder %s: %v", dir, err)
+			return
+		}
+		parent := filepath.Dir(dir)
+		if stopAt == "." || parent == dir || !isWithinDir(parent, stopAt) {
+			return
+		}
+		dir = parent
+	}
+}
+
+func isWithinDir(path string, root string) bool {
+	rel, err := filepath.Rel(root, path)
+	if err != nil {
+		return false
+	}
+	return rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestRenameLeavesUnnamedRowsInPlace(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	src := t.TempDir()
+	root := t.TempDir()
+
+	var tracks []MatchedTrack
+	for _, status := range []string{statusNoMatch, statusAIFailed, "Original", statusUnmatched, statusMatched} {
+		name := status + ".flac"
+		path := filepath.Join(src, name)
+		if err := os.WriteFile(path, []byte(status), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		tracks = append(tracks, MatchedTrack{LocalPath: path, OriginalName: name, ProposedNewName: name, Status: status})
+	}
+
+	report, err := (&App{}).RenameMatchedTracks(tracks, RenameOptions{LibraryRoot: root})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, r := range report.Results {
+		moved := r.Outcome == outcomeRenamed
+		wantMoved := filepath.Base(r.LocalPath) == statusMatched+".flac"
+		if moved != wantMoved {
+			t.Errorf("%s: outcome %q, want moved=%v", r.OriginalName, r.Outcome, wantMoved)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(root, statusMatched+".flac")); err != nil {
+		t.Errorf("matched file was not moved into the library: %v", err)
+	}
+}
+
+func TestPreviewKeepsUnnamedRows(t *testing.T) {
+	track := MatchedTrack{LocalPath: "/in/a:b.flac", OriginalName: "a:b.flac", ProposedNewName: "a:b.flac", Status: statusAIFailed}
+	out, err := (&App{}).PreviewRenames([]MatchedTrack{track}, RenameOptions{FilenameProfile: "windows"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out[0] != track {
+		t.Errorf("preview changed an unnamed row: %+v", out[0])
+	}
+}
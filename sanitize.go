@@ -0,0 +1,238 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	goruntime "runtime"
+	"strings"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+// filenameProfile describes what a target filesystem accepts. Names coming
+// from stores and AI can contain anything, so every proposed name is run
+// through the profile before it is previewed or written.
+type filenameProfile struct {
+	Name string
+	// Characters that are never allowed, with their replacement.
+	Replace map[rune]string
+	// Reject ASCII control characters.
+	NoControl bool
+	// Device names that cannot be used as a file or folder name, with or
+	// without an extension (Windows).
+	Reserved map[string]bool
+	// Names must not end in a dot or a space (Windows).
+	NoTrailingDotSpace bool
+	// Limits on one path component and on the whole path, counted with Units.
+	MaxComponent int
+	MaxPath      int
+	Units        func(string) int
+}
+
+var windowsReplacements = map[rune]string{
+	'<': "(", '>': ")", ':': " -", '"': "'", '/': "-", '\\': "-", '|': "-", '?': "", '*': "",
+}
+
+var windowsReservedNames = map[string]bool{
+	"CON": true, "PRN": true, "AUX": true, "NUL": true,
+	"COM1": true, "COM2": true, "COM3": true, "COM4": true, "COM5": true, "COM6": true, "COM7": true, "COM8": true, "COM9": true,
+	"LPT1": true, "LPT2": true, "LPT3": true, "LPT4": true, "LPT5": true, "LPT6": true, "LPT7": true, "LPT8": true, "LPT9": true,
+}
+
+var filenameProfiles = map[string]*filenameProfile{
+	"posix": {
+		Name:         "POSIX",
+		Replace:      map[rune]string{'/': "-", 0: ""},
+		MaxComponent: 255,
+		MaxPath:      4096,
+		Units:        func(s string) int { return len(s) },
+	},
+	"windows": {
+		Name:               "Windows (NTFS)",
+		Replace:            windowsReplacements,
+		NoControl:          true,
+		Reserved:           windowsReservedNames,
+		NoTrailingDotSpace: true,
+		MaxComponent:       255,
+		MaxPath:            259,
+		Units:              utf16Len,
+	},
+	// FAT32 and exFAT sticks for CDJs: Windows rules, plus DEL is invalid,
+	// and players often choke on long paths, so keep them shorter.
+	"fat32": {
+		Name:               "FAT32 / exFAT",
+		Replace:            mergeReplacements(windowsReplacements, map[rune]string{0x7F: ""}),
+		NoControl:          true,
+		Reserved:           windowsReservedNames,
+		NoTrailingDotSpace: true,
+		MaxComponent:       255,
+		MaxPath:            255,
+		Units:              utf16Len,
+	},
+}
+
+func mergeReplacements(base map[rune]string, extra map[rune]string) map[rune]string {
+	out := make(map[rune]string, len(base)+len(extra))
+	for k, v := range base {
+		out[k] = v
+	}
+	for k, v := range extra {
+		out[k] = v
+	}
+	return out
+}
+
+func utf16Len(s string) int {
+	return len(utf16.Encode([]rune(s)))
+}
+
+// resolveFilenameProfile maps a profile name from the UI to a profile. An
+// empty name or "auto" picks the host platform.
+func resolveFilenameProfile(name string) (*filenameProfile, error) {
+	name = strings.ToLower(strings.TrimSpace(name))
+	if name == "" || name == "auto" {
+		if goruntime.GOOS == "windows" {
+			name = "windows"
+		} else {
+			name = "posix"
+		}
+	}
+	profile, ok := filenameProfiles[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown filename profile %q", name)
+	}
+	return profile, nil
+}
+
+// sanitizeProposedName makes a proposed relative name ("/"-separated) safe
+// for the profile. base is the directory the name will be joined to; it is
+// used to enforce the full path length by shortening the file name. The
+// returned notes describe what was changed.
+func sanitizeProposedName(proposed string, base string, profile *filenameProfile) (string, []string, error) {
+	var notes []string
+	parts := strings.Split(filepath.ToSlash(proposed), "/")
+	kept := make([]string, 0, len(parts))
+	for i, part := range parts {
+		isFile := i == len(parts)-1
+		clean := profile.cleanComponent(part, isFile)
+		if clean == "" {
+			continue
+		}
+		kept = append(kept, clean)
+	}
+	if len(kept) == 0 {
+		return "", nil, fmt.Errorf("name is empty after removing invalid characters")
+	}
+	result := strings.Join(kept, "/")
+	if result != proposed {
+		notes = append(notes, fmt.Sprintf("adjusted for %s", profile.Name))
+	}
+
+	full := filepath.Join(base, filepath.FromSlash(result))
+	if over := profile.Units(full) - profile.MaxPath; over > 0 {
+		file := kept[len(kept)-1]
+		shortened := truncateStem(file, profile.Units(file)-over, profile.Units)
+		if shortened == "" {
+			return result, notes, fmt.Errorf("path is %d characters too long for %s", over, profile.Name)
+		}
+		kept[len(kept)-1] = shortened
+		result = strings.Join(kept, "/")
+		notes = append(notes, fmt.Sprintf("shortened to fit the %s path limit", profile.Name))
+	}
+	return result, notes, nil
+}
+
+func (p *filenameProfile) cleanComponent(s string, isFile bool) string {
+	var b strings.Builder
+	for _, r := range s {
+		if repl, ok := p.Replace[r]; ok {
+			b.WriteString(repl)
+			continue
+		}
+		if p.NoControl && r < 0x20 {
+			continue
+		}
+		if r == utf8.RuneError {
+			continue
+		}
+		b.WriteRune(r)
+	}
+	out := reSpaces.ReplaceAllString(b.String(), " ")
+	out = strings.TrimSpace(out)
+	if p.NoTrailingDotSpace {
+		out = strings.TrimRight(out, ". ")
+	}
+	if out == "." || out == ".." {
+		return ""
+	}
+	if p.Reserved != nil {
+		stem := out
+		if i := strings.Index(stem, "."); i >= 0 {
+			stem = stem[:i]
+		}
+		if p.Reserved[strings.ToUpper(strings.TrimSpace(stem))] {
+			out = strings.TrimSpace(stem) + "_" + out[len(stem):]
+		}
+	}
+	if p.Units(out) > p.MaxComponent {
+		if isFile {
+			out = truncateStem(out, p.MaxComponent, p.Units)
+		} else {
+			out = truncateRunes(out, p.MaxComponent, p.Units)
+		}
+		if p.NoTrailingDotSpace {
+			out = strings.TrimRight(out, ". ")
+		}
+	}
+	return out
+}
+
+// truncateStem shortens a file name to at most max units while keeping its
+// extension. It returns "" when even the extension does not fit.
+func truncateStem(name string, max int, units func(string) int) string {
+	ext := filepath.Ext(name)
+	stem := strings.TrimSuffix(name, ext)
+	room := max - units(ext)
+	if room < 1 {
+		return ""
+	}
+	stem = strings.TrimRight(truncateRunes(stem, room, units), ". ")
+	if stem == "" {
+		return ""
+	}
+	return stem + ext
+}
+
+func truncateRunes(s string, max int, units func(string) int) string {
+	for units(s) > max {
+		_, size := utf8.DecodeLastRuneInString(s)
+		s = s[:len(s)-size]
+	}
+	return strings.TrimSpace(s)
+}
+
+// PreviewRenames checks proposed names against the filename profile and the
+// place they will be written, and returns them as they would be applied.
+// Tracks whose name had to change, or cannot be made to fit, get a warning.
+func (a *App) PreviewRenames(tracks []MatchedTrack, options RenameOptions) ([]MatchedTrack, error) {
+	profile, err := resolveFilenameProfile(options.FilenameProfile)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]MatchedTrack, len(tracks))
+	for i, track := range tracks {
+		if isUnnamedLeftover(track) {
+			out[i] = track
+			continue
+		}
+		name, notes, err := sanitizeProposedName(track.ProposedNewName, targetBase(track, options), profile)
+		if err != nil {
+			track.Warning = err.Error()
+		} else {
+			track.ProposedNewName = name
+			track.Warning = strings.Join(notes, "; ")
+		}
+		out[i] = track
+	}
+	return out, nil
+}
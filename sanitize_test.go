@@ -0,0 +1,86 @@
+package main
+
+import (
+	goruntime "runtime"
+	"strings"
+	"testing"
+)
+
+func TestSanitizeProposedName(t *testing.T) {
+	for _, tc := range []struct {
+		profile  string
+		base     string
+		proposed string
+		want     string
+		changed  bool
+	}{
+		{"posix", "/music", `Kora/Title: "Live" <Remix>.mp3`, `Kora/Title: "Live" <Remix>.mp3`, false},
+		{"windows", "/music", `Kora/Title: "Live" <Remix>*.mp3`, `Kora/Title - 'Live' (Remix).mp3`, true},
+		{"windows", "/music", "CON.mp3", "CON_.mp3", true},
+		{"windows", "/music", "aux/01 Intro.mp3", "aux_/01 Intro.mp3", true},
+		{"windows", "/music", "Vol. 1./Intro.mp3. ", "Vol. 1/Intro.mp3", true},
+		{"windows", "/music", "Intro\x01  Outro.mp3", "Intro Outro.mp3", true},
+		{"fat32", "/music", "Intro\x7f.mp3", "Intro.mp3", true},
+		// Names can never climb out of the base folder.
+		{"posix", "/music", "../../etc/01.mp3", "etc/01.mp3", true},
+		// A component over the limit loses whole characters, not bytes, and
+		// keeps its extension.
+		{"posix", "/music", strings.Repeat("é", 200) + ".mp3", strings.Repeat("é", 125) + ".mp3", true},
+	} {
+		profile, err := resolveFilenameProfile(tc.profile)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, notes, err := sanitizeProposedName(tc.proposed, tc.base, profile)
+		if err != nil {
+			t.Errorf("%s %q: %v", tc.profile, tc.proposed, err)
+			continue
+		}
+		if got != tc.want || (len(notes) > 0) != tc.changed {
+			t.Errorf("%s %q: got %q with notes %q, want %q", tc.profile, tc.proposed, got, notes, tc.want)
+		}
+	}
+}
+
+func TestSanitizeProposedNamePathLimit(t *testing.T) {
+	fat32, _ := resolveFilenameProfile("fat32")
+	base := "/" + strings.Repeat("a", 200)
+
+	// The whole path must fit, so the file name is shortened.
+	got, notes, err := sanitizeProposedName(strings.Repeat("b", 100)+".mp3", base, fat32)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := strings.Repeat("b", 49) + ".mp3"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+	if len(notes) != 1 || !strings.HasPrefix(notes[0], "shortened") {
+		t.Errorf("notes = %q", notes)
+	}
+
+	// Not even the extension fits.
+	if _, _, err := sanitizeProposedName("01.mp3", "/"+strings.Repeat("a", 252), fat32); err == nil {
+		t.Error("expected an error for a base path at the limit")
+	}
+	if _, _, err := sanitizeProposedName("??/*", base, fat32); err == nil {
+		t.Error("expected an error for a name made only of invalid characters")
+	}
+}
+
+func TestResolveFilenameProfile(t *testing.T) {
+	for name, want := range map[string]string{
+		"windows": "Windows (NTFS)",
+		" FAT32 ": "FAT32 / exFAT",
+		"posix":   "POSIX",
+		"":        map[bool]string{true: "Windows (NTFS)", false: "POSIX"}[goruntime.GOOS == "windows"],
+		"auto":    map[bool]string{true: "Windows (NTFS)", false: "POSIX"}[goruntime.GOOS == "windows"],
+	} {
+		profile, err := resolveFilenameProfile(name)
+		if err != nil || profile.Name != want {
+			t.Errorf("%q: got %v, %v, want %s", name, profile, err, want)
+		}
+	}
+	if _, err := resolveFilenameProfile("hfs"); err == nil {
+		t.Error("expected an error for an unknown profile")
+	}
+}
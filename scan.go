@@ -0,0 +1,207 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/dhowden/tag"
+	"github.com/wailsapp/wails/v2/pkg/runtime"
+)
+
+// AlbumGroup is the set of audio files found directly inside one directory.
+// A recursive scan produces one group per directory that contains audio, so
+// "Release A/", "Release B/CD1" and "Release B/CD2" are handled as separate albums.
+type AlbumGroup struct {
+	Dir    string       `json:"dir"`
+	Name   string       `json:"name"`
+	Tracks []LocalTrack `json:"tracks"`
+}
+
+type FolderScan struct {
+	Root    string        `json:"root"`
+	Groups  []AlbumGroup  `json:"groups"`
+	Skipped []SkippedFile `json:"skipped"`
+}
+
+// SelectFolderRecursive asks for a root folder and scans it and every
+// subdirectory, grouping the tracks by the directory that contains them.
+func (a *App) SelectFolderRecursive() (*FolderScan, error) {
+	dirPath, err := runtime.OpenDirectoryDialog(a.ctx, runtime.OpenDialogOptions{
+		Title: "Select Library Folder",
+	})
+	if err != nil {
+		return nil, err
+	}
+	if dirPath == "" {
+		return &FolderScan{Groups: []AlbumGroup{}, Skipped: []SkippedFile{}}, nil
+	}
+	ctx, done := a.startJob("scan")
+	defer done()
+	scan, err := scanFolder(ctx, dirPath, true)
+	return scan, cancelledErr(ctx, err)
+}
+
+// GenerateGroupRenames runs GenerateTemplateRenames once per album group and
+// returns the combined results in group order.
+func (a *App) GenerateGroupRenames(groups []AlbumGroup, format string) ([]MatchedTrack, error) {
+	var matchedTracks []MatchedTrack
+	for _, group := range groups {
+		tracks, err := a.GenerateTemplateRenames(group.Tracks, format)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", group.Name, err)
+		}
+		matchedTracks = append(matchedTracks, tracks...)
+	}
+	return matchedTracks, nil
+}
+
+// FetchAndMatchGroups matches every album group that has a release URL
+// assigned in urls (keyed by AlbumGroup.Dir). Groups without a URL are skipped.
+// Missing tracks are labelled with the group they belong to.
+func (a *App) FetchAndMatchGroups(groups []AlbumGroup, urls map[string]string, pattern string) (*MatchResult, error) {
+	ctx, done := a.startJob("fetch")
+	defer done()
+	combined := &MatchResult{Tracks: []MatchedTrack{}, Missing: []MissingTrack{}}
+	for _, group := range groups {
+		url := strings.TrimSpace(urls[group.Dir])
+		if url == "" {
+			continue
+		}
+		result, err := a.fetchAndMatch(ctx, url, group.Tracks, pattern)
+		if errors.Is(err, errJobCancelled) {
+			return nil, err
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", group.Name, err)
+		}
+		combined.Tracks = append(combined.Tracks, result.Tracks...)
+		for _, missing := range result.Missing {
+			missing.Group = group.Name
+			combined.Missing = append(combined.Missing, missing)
+		}
+	}
+	return combined, nil
+}
+
+func scanFolder(ctx context.Context, root string, recursive bool) (*FolderScan, error) {
+	scan := &FolderScan{Root: root, Groups: []AlbumGroup{}, Skipped: []SkippedFile{}}
+	if !recursive {
+		group, err := scanGroup(ctx, root, root, scan)
+		if err != nil {
+			return nil, err
+		}
+		if len(group.Tracks) > 0 {
+			scan.Groups = append(scan.Groups, group)
+		}
+		return scan, nil
+	}
+
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if path == root {
+				return err
+			}
+			// Unreadable subdirectories should not abort the whole scan.
+			return fs.SkipDir
+		}
+		if !d.IsDir() {
+			return nil
+		}
+		if path != root && strings.HasPrefix(d.Name(), ".") {
+			return fs.SkipDir
+		}
+		group, err := scanGroup(ctx, root, path, scan)
+		if err != nil {
+			// Only a cancelled scan stops the walk.
+			return ctx.Err()
+		}
+		if len(group.Tracks) > 0 {
+			scan.Groups = append(scan.Groups, group)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.SliceStable(scan.Groups, func(i, j int) bool {
+		return scan.Groups[i].Dir < scan.Groups[j].Dir
+	})
+	return scan, nil
+}
+
+func scanGroup(ctx context.Context, root string, dirPath string, scan *FolderScan) (AlbumGroup, error) {
+	name, err := filepath.Rel(root, dirPath)
+	if err != nil || name == "." {
+		name = filepath.Base(dirPath)
+	}
+	group := AlbumGroup{Dir: dirPath, Name: filepath.ToSlash(name)}
+
+	files, err := os.ReadDir(dirPath)
+	if err != nil {
+		return group, err
+	}
+	for _, file := range files {
+		if err := ctx.Err(); err != nil {
+			return group, err
+		}
+		if file.IsDir() {
+			continue
+		}
+		name := file.Name()
+		if strings.HasPrefix(name, ".") || ignoredExts[strings.ToLower(filepath.Ext(name))] {
+			continue
+		}
+		path := filepath.Join(dirPath, name)
+		format, reason := detectAudioFormat(path)
+		if format == nil {
+			scan.Skipped = append(scan.Skipped, SkippedFile{Path: path, Name: name, Reason: reason})
+			continue
+		}
+		track := readLocalTrack(path)
+		track.Format = format.Name
+		track.Duration = readAudioDuration(path, format)
+		track.Group = group.Name
+		group.Tracks = append(group.Tracks, track)
+	}
+	return group, nil
+}
+
+func readLocalTrack(path string) LocalTrack {
+	track := LocalTrack{
+		Path:         path,
+		OriginalName: filepath.Base(path),
+	}
+
+	// Try to read tags
+	f, err := os.Open(path)
+	if err == nil {
+		m, err := tag.ReadFrom(f)
+		if err == nil {
+			applyTagMetadata(&track, m)
+		}
+		f.Close()
+	}
+	return track
+}
+
+func applyTagMetadata(track *LocalTrack, m tag.Metadata) {
+	track.TagArtist = strings.TrimSpace(m.Artist())
+	track.TagTitle = strings.TrimSpace(m.Title())
+	track.TagAlbum = strings.TrimSpace(m.Album())
+	track.TagAlbumArtist = strings.TrimSpace(m.AlbumArtist())
+	track.TagTrack, track.TagTrackTotal = m.Track()
+	track.TagDisc, track.TagDiscTotal = m.Disc()
+	track.TagYear = m.Year()
+	track.TagGenre = strings.TrimSpace(m.Genre())
+	track.TagComposer = strings.TrimSpace(m.Composer())
+	if pic := m.Picture(); pic != nil && len(pic.Data) > 0 {
+		track.HasPicture = true
+		track.PictureMIME = pic.MIMEType
+	}
+}
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/dhowden/tag"
+)
+
+// libraryTree lays out a small library: loose files in the root, one
+// release per folder, a multi-disc release and a hidden folder.
+func libraryTree(t *testing.T) string {
+	t.Helper()
+	root := filepath.Join(t.TempDir(), "Library")
+	for _, dir := range []string{"Release A", "Release B/CD1", "Release B/CD2", ".hidden"} {
+		if err := os.MkdirAll(filepath.Join(root, dir), 0o755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	flac := "fLaC" + strings.Repeat("\x00", 64)
+	wav := "RIFF\x00\x00\x00\x00WAVE" + strings.Repeat("\x00", 64)
+	writeFiles(t, root, map[string]string{
+		"loose.flac":            flac,
+		"Release A/01.flac":     flac,
+		"Release A/cover.jpg":   "jpeg",
+		"Release A/.DS_Store":   "junk",
+		"Release A/notes.doc":   "not audio",
+		"Release B/CD1/01.flac": flac,
+		"Release B/CD2/01.wav":  wav,
+		".hidden/01.flac":       flac,
+	})
+	return root
+}
+
+func TestScanFolderRecursive(t *testing.T) {
+	root := libraryTree(t)
+
+	scan, err := scanFolder(context.Background(), root, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"Library", "Release A", "Release B/CD1", "Release B/CD2"}
+	if len(scan.Groups) != len(want) {
+		t.Fatalf("got %d groups %+v, want %q", len(scan.Groups), scan.Groups, want)
+	}
+	for i, group := range scan.Groups {
+		if group.Name != want[i] {
+			t.Errorf("group %d = %q, want %q", i, group.Name, want[i])
+		}
+		if len(group.Tracks) != 1 {
+			t.Errorf("group %q has %d tracks, want 1", group.Name, len(group.Tracks))
+			continue
+		}
+		if track := group.Tracks[0]; track.Group != group.Name || track.Format == "" {
+			t.Errorf("group %q track = %+v, want its group and format set", group.Name, track)
+		}
+	}
+	if len(scan.Skipped) != 1 || scan.Skipped[0].Name != "notes.doc" {
+		t.Errorf("skipped = %+v, want only notes.doc", scan.Skipped)
+	}
+}
+
+func TestScanFolderNonRecursive(t *testing.T) {
+	root := libraryTree(t)
+
+	scan, err := scanFolder(context.Background(), root, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(scan.Groups) != 1 || scan.Groups[0].Name != "Library" || len(scan.Groups[0].Tracks) != 1 {
+		t.Fatalf("groups = %+v, want only the root with loose.flac", scan.Groups)
+	}
+}
+
+func TestScanFolderCancelled(t *testing.T) {
+	root := libraryTree(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if _, err := scanFolder(ctx, root, true); err == nil {
+		t.Fatal("cancelled scan returned no error")
+	}
+}
+
+// fakeTags is the tag.Metadata of a fully tagged file.
+type fakeTags struct{}
+
+func (fakeTags) Format() tag.Format          { return tag.VORBIS }
+func (fakeTags) FileType() tag.FileType      { return tag.FLAC }
+func (fakeTags) Title() string               { return " Tunnel Lights " }
+func (fakeTags) Album() string               { return "Night Drive" }
+func (fakeTags) Artist() string              { return "Kora" }
+func (fakeTags) AlbumArtist() string         { return "Various Artists" }
+func (fakeTags) Composer() string            { return "K. Ora" }
+func (fakeTags) Year() int                   { return 2019 }
+func (fakeTags) Genre() string               { return "Techno" }
+func (fakeTags) Track() (int, int)           { return 3, 12 }
+func (fakeTags) Disc() (int, int)            { return 2, 2 }
+func (fakeTags) Lyrics() string              { return "" }
+func (fakeTags) Comment() string             { return "" }
+func (fakeTags) Raw() map[string]interface{} { return nil }
+func (fakeTags) Picture() *tag.Picture {
+	return &tag.Picture{MIMEType: "image/jpeg", Data: []byte{0xff, 0xd8}}
+}
+
+func TestApplyTagMetadata(t *testing.T) {
+	var track LocalTrack
+	applyTagMetadata(&track, fakeTags{})
+
+	want := LocalTrack{
+		TagArtist: "Kora", TagTitle: "Tunnel Lights", TagAlbum: "Night Drive", TagAlbumArtist: "Various Artists",
+		TagTrack: 3, TagTrackTotal: 12, TagDisc: 2, TagDiscTotal: 2, TagYear: 2019,
+		TagGenre: "Techno", TagComposer: "K. Ora", HasPicture: true, PictureMIME: "image/jpeg",
+	}
+	if track != want {
+		t.Errorf("got %+v, want %+v", track, want)
+	}
+}
@@ -0,0 +1,210 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/adrg/strutil"
+	"github.com/adrg/strutil/metrics"
+)
+
+const (
+	// maxSuggestQueries caps the queries sent to each store.
+	maxSuggestQueries = 3
+	// maxSuggestFetch is how many of the best search results are downloaded
+	// to compare their track lists with the folder.
+	maxSuggestFetch = 8
+	// maxSuggestions caps the returned list.
+	maxSuggestions = 15
+
+	suggestOverlapWeight = 0.6
+	suggestCountWeight   = 0.25
+	suggestNameWeight    = 0.15
+)
+
+// Folder names often carry a catalog number or year in brackets.
+var reBracketed = regexp.MustCompile(`\s*[\[(][^\])]*[\])]`)
+
+// SuggestReleases finds the releases a folder most likely came from, without
+// a URL: it searches every registered store with queries built from the tags
+// and filenames, and ranks the results by how well their track count and
+// track titles agree with the folder.
+func (a *App) SuggestReleases(localTracks []LocalTrack) ([]ReleaseSummary, error) {
+	if len(localTracks) == 0 {
+		return nil, fmt.Errorf("no local tracks to search for")
+	}
+	releaseName, queries := suggestQueries(localTracks)
+	if len(queries) == 0 {
+		return nil, fmt.Errorf("could not work out what to search for from the tags or filenames")
+	}
+	log.Printf("Searching stores for: %q", queries)
+
+	ctx, done := a.startJob("suggest")
+	defer done()
+	candidates := a.searchProviders(ctx, queries)
+	if err := cancelledErr(ctx, ctx.Err()); err != nil {
+		return nil, err
+	}
+	if len(candidates) == 0 {
+		return nil, fmt.Errorf("no store returned any results for %q", queries[0])
+	}
+
+	sm := metrics.NewSorensenDice()
+	sm.CaseSensitive = false
+	nameScore := func(r ReleaseSummary) float64 {
+		if releaseName == "" {
+			return 0
+		}
+		return strutil.Similarity(normalizeForMatch(r.Artist+" "+r.Title), normalizeForMatch(releaseName), sm)
+	}
+	rank := func() {
+		sort.SliceStable(candidates, func(i, j int) bool { return candidates[i].Score > candidates[j].Score })
+	}
+
+	// First rank on what the search results say, then download the best few
+	// and compare their track lists with the folder.
+	for i := range candidates {
+		candidates[i].Score = suggestCountWeight*trackCountAgreement(candidates[i].TrackCount, len(localTracks)) +
+			suggestNameWeight*nameScore(candidates[i])
+	}
+	rank()
+	top := candidates[:min(len(candidates), maxSuggestFetch)]
+	overlaps := a.compareReleases(ctx, top, localTracks)
+	if err := cancelledErr(ctx, ctx.Err()); err != nil {
+		return nil, err
+	}
+	for i := range top {
+		if overlaps[i] < 0 {
+			// Unreachable releases sink to the bottom.
+			top[i].Score = 0
+			continue
+		}
+		top[i].Score = suggestOverlapWeight*overlaps[i] +
+			suggestCountWeight*trackCountAgreement(top[i].TrackCount, len(localTracks)) +
+			suggestNameWeight*nameScore(top[i])
+	}
+	rank()
+	return candidates[:min(len(candidates), maxSuggestions)], nil
+}
+
+// suggestQueries returns the most likely "artist album" of the folder, if the
+// tags or folder name tell, and the search queries to try, best first.
+func suggestQueries(localTracks []LocalTrack) (string, []string) {
+	artist := mostCommon(localTracks, func(t LocalTrack) string { return firstNonEmpty(t.TagAlbumArtist, t.TagArtist) })
+	album := mostCommon(localTracks, func(t LocalTrack) string { return t.TagAlbum })
+
+	var queries []string
+	seen := map[string]bool{}
+	add := func(parts ...string) {
+		q := cleanText(strings.Join(parts, " "))
+		if q == "" || seen[strings.ToLower(q)] || len(queries) >= maxSuggestQueries {
+			return
+		}
+		seen[strings.ToLower(q)] = true
+		queries = append(queries, q)
+	}
+
+	releaseName := ""
+	if album != "" {
+		releaseName = cleanText(artist + " " + album)
+		add(artist, album)
+	}
+
+	// The folder is usually named after the release: "Artist - Album [CAT01]".
+	folder := mostCommon(localTracks, func(t LocalTrack) string { return filepath.Base(filepath.Dir(t.Path)) })
+	folder = cleanText(strings.NewReplacer("_", " ", " - ", " ").Replace(reBracketed.ReplaceAllString(folder, "")))
+	if folder != "" && folder != "." && !reDigitsOnly.MatchString(folder) {
+		if releaseName == "" {
+			releaseName = folder
+		}
+		add(folder)
+	}
+
+	// Without usable tags or folder, search for a track: the artist and title
+	// parsed from the first filename that yields both.
+	for _, t := range localTracks {
+		cand := buildTemplateCandidate(t)
+		if cand.Artist != "" && cand.Title != "" {
+			add(cand.Artist, cand.Title)
+			break
+		}
+	}
+	return releaseName, queries
+}
+
+// mostCommon returns the most frequent non-empty value of field, preferring
+// the earliest on a tie.
+func mostCommon(localTracks []LocalTrack, field func(LocalTrack) string) string {
+	counts := map[string]int{}
+	best := ""
+	for _, t := range localTracks {
+		v := strings.TrimSpace(field(t))
+		if v == "" {
+			continue
+		}
+		counts[v]++
+		if counts[v] > counts[best] {
+			best = v
+		}
+	}
+	return best
+}
+
+// trackCountAgreement is 1 when a release has as many tracks as the folder
+// and falls off with the difference. An unknown count scores 0.5.
+func trackCountAgreement(releaseTracks int, localTracks int) float64 {
+	if releaseTracks <= 0 {
+		return 0.5
+	}
+	diff := releaseTracks - localTracks
+	if diff < 0 {
+		diff = -diff
+	}
+	return 1 - float64(diff)/float64(max(releaseTracks, localTracks))
+}
+
+// searchProviders runs every query against every registered provider at
+// once. Results are deduplicated by URL and kept in the order the stores
+// returned them. Stores that fail (Discogs without a token, say) are logged
+// and skipped.
+func (a *App) searchProviders(ctx context.Context, queries []string) []ReleaseSummary {
+	results := make([][][]ReleaseSummary, len(metadataProviders))
+	var wg sync.WaitGroup
+	for i, provider := range metadataProviders {
+		results[i] = make([][]ReleaseSummary, len(queries))
+		for q, query := range queries {
+			wg.Add(1)
+			go func(i, q int, provider MetadataProvider, query string) {
+				defer wg.Done()
+				found, err := provider.Search(ctx, query)
+				if err != nil {
+					log.Printf("%s search for %q failed: %v", provider.Name(), query, err)
+					return
+				}
+				results[i][q] = found
+			}(i, q, provider, query)
+		}
+	}
+	wg.Wait()
+
+	var candidates []ReleaseSummary
+	seen := map[string]bool{}
+	for q := range queries {
+		for i := range metadataProviders {
+			for _, r := range results[i][q] {
+				if r.URL == "" || seen[r.URL] {
+					continue
+				}
+				seen[r.URL] = true
+				candidates = append(candidates, r)
+			}
+		}
+	}
+	return candidates
+}
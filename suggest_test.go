@@ -0,0 +1,154 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// suggestTestProvider is a store whose search returns results and whose
+// release pages are albums; results without an album fail to fetch.
+type suggestTestProvider struct {
+	results []ReleaseSummary
+	albums  map[string]*AlbumData
+}
+
+func (p suggestTestProvider) Name() string { return "Test Store" }
+
+func (p suggestTestProvider) CanHandle(url string) bool {
+	return strings.HasPrefix(url, "https://store.test/")
+}
+
+func (p suggestTestProvider) Fetch(ctx context.Context, url string) (*AlbumData, error) {
+	if album, ok := p.albums[url]; ok {
+		return album, nil
+	}
+	return nil, fmt.Errorf("%s: not found", url)
+}
+
+func (p suggestTestProvider) Search(ctx context.Context, query string) ([]ReleaseSummary, error) {
+	return p.results, nil
+}
+
+func suggestTestTracks() []LocalTrack {
+	var tracks []LocalTrack
+	for i, title := range []string{"Sunrise", "Moonlight", "Thunderstorm"} {
+		name := fmt.Sprintf("%02d Artist - %s.flac", i+1, title)
+		tracks = append(tracks, LocalTrack{
+			Path:           "/music/Artist - Weather [CAT01]/" + name,
+			OriginalName:   name,
+			TagArtist:      "Artist",
+			TagAlbumArtist: "Artist",
+			TagAlbum:       "Weather",
+			TagTitle:       title,
+			TagTrack:       i + 1,
+		})
+	}
+	return tracks
+}
+
+func TestSuggestQueries(t *testing.T) {
+	for _, tc := range []struct {
+		name        string
+		tracks      []LocalTrack
+		wantRelease string
+		wantQueries []string
+	}{
+		{
+			name:        "tags",
+			tracks:      suggestTestTracks(),
+			wantRelease: "Artist Weather",
+			wantQueries: []string{"Artist Weather", "Artist Sunrise"},
+		},
+		{
+			name: "folder name",
+			tracks: []LocalTrack{
+				{Path: "/music/Some_Artist - Some Album (2019)/01.flac", OriginalName: "01.flac"},
+			},
+			wantRelease: "Some Artist Some Album",
+			wantQueries: []string{"Some Artist Some Album"},
+		},
+		{
+			name: "filename only",
+			tracks: []LocalTrack{
+				{Path: "/music/1/01 Artist - Sunrise.flac", OriginalName: "01 Artist - Sunrise.flac"},
+			},
+			wantRelease: "",
+			wantQueries: []string{"Artist Sunrise"},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			release, queries := suggestQueries(tc.tracks)
+			if release != tc.wantRelease {
+				t.Errorf("release = %q, want %q", release, tc.wantRelease)
+			}
+			if !reflect.DeepEqual(queries, tc.wantQueries) {
+				t.Errorf("queries = %q, want %q", queries, tc.wantQueries)
+			}
+		})
+	}
+}
+
+func TestTrackCountAgreement(t *testing.T) {
+	for _, tc := range []struct {
+		release, local int
+		want           float64
+	}{
+		{10, 10, 1},
+		{0, 10, 0.5},
+		{5, 10, 0.5},
+		{10, 5, 0.5},
+		{12, 9, 0.75},
+	} {
+		if got := trackCountAgreement(tc.release, tc.local); got != tc.want {
+			t.Errorf("trackCountAgreement(%d, %d) = %v, want %v", tc.release, tc.local, got, tc.want)
+		}
+	}
+}
+
+func TestSuggestReleasesRanksByTrackOverlap(t *testing.T) {
+	saved := metadataProviders
+	defer func() { metadataProviders = saved }()
+	metadataProviders = []MetadataProvider{suggestTestProvider{
+		results: []ReleaseSummary{
+			// Named like the folder, but a different track list.
+			{URL: "https://store.test/namesake", Artist: "Artist", Title: "Weather", TrackCount: 3},
+			{URL: "https://store.test/gone", Artist: "Artist", Title: "Weather (Deluxe)", TrackCount: 3},
+			// Named differently, but the folder's tracks.
+			{URL: "https://store.test/right", Artist: "Artist", Title: "Forecast"},
+		},
+		albums: map[string]*AlbumData{
+			"https://store.test/namesake": {Artist: "Artist", Title: "Weather", Tracks: []AlbumTrack{
+				{Title: "Glacier", TrackNum: 1, TrackNumExplicit: true},
+				{Title: "Desert", TrackNum: 2, TrackNumExplicit: true},
+				{Title: "Tundra", TrackNum: 3, TrackNumExplicit: true},
+			}},
+			"https://store.test/right": {Artist: "Artist", Title: "Forecast", Tracks: []AlbumTrack{
+				{Title: "Sunrise", TrackNum: 1, TrackNumExplicit: true},
+				{Title: "Moonlight", TrackNum: 2, TrackNumExplicit: true},
+				{Title: "Thunderstorm", TrackNum: 3, TrackNumExplicit: true},
+			}},
+		},
+	}}
+
+	suggestions, err := (&App{}).SuggestReleases(suggestTestTracks())
+	if err != nil {
+		t.Fatal(err)
+	}
+	var urls []string
+	for _, s := range suggestions {
+		urls = append(urls, s.URL)
+	}
+	want := []string{"https://store.test/right", "https://store.test/namesake", "https://store.test/gone"}
+	if !reflect.DeepEqual(urls, want) {
+		t.Fatalf("order = %q, want %q", urls, want)
+	}
+	if right := suggestions[0]; right.TrackCount != 3 || right.Score <= 0.9 {
+		t.Errorf("best match = %+v, want its track count filled in and a score above 0.9", right)
+	}
+	if gone := suggestions[2]; gone.Score != 0 {
+		t.Errorf("unreachable release scored %v, want 0", gone.Score)
+	}
+}
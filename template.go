@@ -0,0 +1,322 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Rename templates use a small token language:
+//
+//	{track:02}. {artist} - {title}{bpm?( ({bpm} bpm))}
+//
+//   - {name}            value of a field
+//   - {name:02}         zero-pad numeric values to the given width
+//   - {name|upper}      apply filters: upper, lower, title, sentence, trim
+//   - {name?(...)}      render the bracketed section only when name is set
+//   - {{ and }}         literal braces
+//   - a/b               "/" (or "\\") in the pattern starts a new folder
+//
+// The file extension is appended after rendering. A "/" inside a field value
+// never creates a folder; it is replaced with "-".
+
+const defaultRenameTemplate = "{disc?({disc}-)}{track?({track:02}. )}{artist?({artist} - )}{title}{bpmtag?( {bpmtag})}"
+
+// Legacy format names accepted by GenerateTemplateRenames before templates
+// existed. They keep producing the same names, so unlike the default they
+// never prefix the disc number.
+var namedTemplates = map[string]string{
+	"Track. Artist - Title": "{track?({track:02}. )}{artist?({artist} - )}{title}{bpmtag?( {bpmtag})}",
+	"Track. Title":          "{track?({track:02}. )}{title}{bpmtag?( {bpmtag})}",
+}
+
+var templateTokens = map[string]bool{
+	"track": true, "tracktotal": true, "disc": true, "disctotal": true,
+	"artist": true, "title": true, "album": true, "albumartist": true,
+	"year": true, "genre": true, "composer": true,
+	"bpm": true, "bpmtag": true, "original": true,
+	"label": true, "catno": true, "position": true, "isrc": true,
+}
+
+var templateFilters = map[string]func(string) string{
+	"upper":    strings.ToUpper,
+	"lower":    strings.ToLower,
+	"trim":     strings.TrimSpace,
+	"title":    titleCase,
+	"sentence": sentenceCase,
+}
+
+// TemplateFields holds the values available to a rename template, keyed by
+// token name. Missing keys render as empty strings.
+type TemplateFields map[string]string
+
+type templateNode struct {
+	literal  string
+	field    string
+	width    int
+	zeroPad  bool
+	filters  []func(string) string
+	optional []templateNode
+	isOpt    bool
+}
+
+type renameTemplate struct {
+	pattern string
+	nodes   []templateNode
+}
+
+// resolveRenameTemplate turns a user supplied pattern (or one of the legacy
+// format names) into a parsed template. An empty pattern uses the default.
+func resolveRenameTemplate(pattern string) (*renameTemplate, error) {
+	pattern = strings.TrimSpace(pattern)
+	if pattern == "" {
+		pattern = defaultRenameTemplate
+	}
+	if named, ok := namedTemplates[pattern]; ok {
+		pattern = named
+	}
+	return parseRenameTemplate(pattern)
+}
+
+func parseRenameTemplate(pattern string) (*renameTemplate, error) {
+	nodes, rest, err := parseTemplateNodes(pattern, false)
+	if err != nil {
+		return nil, fmt.Errorf("invalid template %q: %w", pattern, err)
+	}
+	if rest != "" {
+		return nil, fmt.Errorf("invalid template %q: unexpected %q", pattern, rest)
+	}
+	return &renameTemplate{pattern: pattern, nodes: nodes}, nil
+}
+
+// parseTemplateNodes parses until the end of s, or until the closing ")" of
+// an optional section when nested is set. It returns the unparsed remainder.
+func parseTemplateNodes(s string, nested bool) ([]templateNode, string, error) {
+	var nodes []templateNode
+	var lit strings.Builder
+	depth := 0
+	flush := func() {
+		if lit.Len() > 0 {
+			nodes = append(nodes, templateNode{literal: lit.String()})
+			lit.Reset()
+		}
+	}
+	for len(s) > 0 {
+		switch {
+		case strings.HasPrefix(s, "{{"):
+			lit.WriteByte('{')
+			s = s[2:]
+		case strings.HasPrefix(s, "}}"):
+			lit.WriteByte('}')
+			s = s[2:]
+		case s[0] == '{':
+			flush()
+			node, rest, err := parseTemplateToken(s[1:])
+			if err != nil {
+				return nil, "", err
+			}
+			nodes = append(nodes, node)
+			s = rest
+		case s[0] == '}':
+			return nil, "", fmt.Errorf("unmatched '}'")
+		case nested && s[0] == '(':
+			depth++
+			lit.WriteByte('(')
+			s = s[1:]
+		case nested && s[0] == ')':
+			if depth == 0 {
+				flush()
+				return nodes, s, nil
+			}
+			depth--
+			lit.WriteByte(')')
+			s = s[1:]
+		default:
+			lit.WriteByte(s[0])
+			s = s[1:]
+		}
+	}
+	if nested {
+		return nil, "", fmt.Errorf("unclosed optional section")
+	}
+	flush()
+	return nodes, "", nil
+}
+
+// parseTemplateToken parses the inside of a {...} token; s starts right
+// after the opening brace.
+func parseTemplateToken(s string) (templateNode, string, error) {
+	end := strings.IndexAny(s, "}?")
+	if end < 0 {
+		return templateNode{}, "", fmt.Errorf("unclosed '{'")
+	}
+	spec := s[:end]
+	node, err := parseTokenSpec(spec)
+	if err != nil {
+		return templateNode{}, "", err
+	}
+	if s[end] == '}' {
+		return node, s[end+1:], nil
+	}
+
+	// Optional section: {name?(...)}
+	rest := strings.TrimLeft(s[end+1:], " ")
+	if !strings.HasPrefix(rest, "(") {
+		return templateNode{}, "", fmt.Errorf("expected '(' after '%s?'", node.field)
+	}
+	inner, rest, err := parseTemplateNodes(rest[1:], true)
+	if err != nil {
+		return templateNode{}, "", err
+	}
+	rest = strings.TrimLeft(rest[1:], " ")
+	if !strings.HasPrefix(rest, "}") {
+		return templateNode{}, "", fmt.Errorf("expected '}' after optional section for %s", node.field)
+	}
+	node.isOpt = true
+	node.optional = inner
+	return node, rest[1:], nil
+}
+
+func parseTokenSpec(spec string) (templateNode, error) {
+	parts := strings.Split(spec, "|")
+	head := strings.TrimSpace(parts[0])
+	node := templateNode{}
+	if i := strings.Index(head, ":"); i >= 0 {
+		pad := strings.TrimSpace(head[i+1:])
+		head = strings.TrimSpace(head[:i])
+		width, err := strconv.Atoi(pad)
+		if err != nil || width < 0 {
+			return node, fmt.Errorf("invalid padding %q for %s", pad, head)
+		}
+		node.width = width
+		node.zeroPad = strings.HasPrefix(pad, "0")
+	}
+	node.field = strings.ToLower(head)
+	if !templateTokens[node.field] {
+		return node, fmt.Errorf("unknown token %q", head)
+	}
+	for _, name := range parts[1:] {
+		name = strings.ToLower(strings.TrimSpace(name))
+		filter, ok := templateFilters[name]
+		if !ok {
+			return node, fmt.Errorf("unknown filter %q", name)
+		}
+		node.filters = append(node.filters, filter)
+	}
+	return node, nil
+}
+
+var valueSeparatorReplacer = strings.NewReplacer("/", "-", "\\", "-")
+
+// Render fills the template and appends ext. The result uses "/" between
+// folders. Runs of whitespace left behind by empty tokens are collapsed, and
+// folder levels that rendered empty are dropped.
+func (t *renameTemplate) Render(fields TemplateFields, ext string) string {
+	var b strings.Builder
+	renderTemplateNodes(&b, t.nodes, fields)
+	rendered := strings.ReplaceAll(b.String(), "\\", "/")
+	segments := strings.Split(rendered, "/")
+	kept := segments[:0]
+	for _, segment := range segments {
+		segment = reSpaces.ReplaceAllString(segment, " ")
+		segment = strings.TrimSpace(segment)
+		if segment != "" {
+			kept = append(kept, segment)
+		}
+	}
+	return strings.Join(kept, "/") + ext
+}
+
+func renderTemplateNodes(b *strings.Builder, nodes []templateNode, fields TemplateFields) {
+	for _, node := range nodes {
+		if node.field == "" {
+			b.WriteString(node.literal)
+			continue
+		}
+		value := strings.TrimSpace(valueSeparatorReplacer.Replace(fields[node.field]))
+		if node.isOpt {
+			if value != "" && value != "0" {
+				renderTemplateNodes(b, node.optional, fields)
+			}
+			continue
+		}
+		b.WriteString(node.format(value))
+	}
+}
+
+func (n templateNode) format(value string) string {
+	if n.width > 0 && value != "" {
+		if num, err := strconv.Atoi(value); err == nil {
+			if n.zeroPad {
+				value = fmt.Sprintf("%0*d", n.width, num)
+			} else {
+				value = fmt.Sprintf("%*d", n.width, num)
+			}
+		}
+	}
+	for _, filter := range n.filters {
+		value = filter(value)
+	}
+	return value
+}
+
+func titleCase(s string) string {
+	runes := []rune(strings.ToLower(s))
+	start := true
+	for i, r := range runes {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			if start {
+				runes[i] = unicode.ToUpper(r)
+			}
+			start = false
+		} else {
+			start = r != '\''
+		}
+	}
+	return string(runes)
+}
+
+func sentenceCase(s string) string {
+	runes := []rune(strings.ToLower(s))
+	for i, r := range runes {
+		if unicode.IsLetter(r) {
+			runes[i] = unicode.ToUpper(r)
+			break
+		}
+	}
+	return string(runes)
+}
+
+func intField(n int) string {
+	if n <= 0 {
+		return ""
+	}
+	return strconv.Itoa(n)
+}
+
+// localTemplateFields seeds the template fields from the file itself: its
+// tags and original name. Callers override artist/title/track with parsed
+// or fetched values. The disc is only filled in for multi-disc releases, so
+// "{disc?({disc}-)}" does not prefix every single-disc album with "1-".
+func localTemplateFields(local LocalTrack) TemplateFields {
+	disc, discTotal := local.TagDisc, local.TagDiscTotal
+	if disc <= 1 && discTotal <= 1 {
+		disc, discTotal = 0, 0
+	}
+	return TemplateFields{
+		"track":       intField(local.TagTrack),
+		"tracktotal":  intField(local.TagTrackTotal),
+		"disc":        intField(disc),
+		"disctotal":   intField(discTotal),
+		"artist":      local.TagArtist,
+		"title":       local.TagTitle,
+		"album":       local.TagAlbum,
+		"albumartist": local.TagAlbumArtist,
+		"year":        intField(local.TagYear),
+		"genre":       local.TagGenre,
+		"composer":    local.TagComposer,
+		"original":    strings.TrimSuffix(local.OriginalName, filepath.Ext(local.OriginalName)),
+	}
+}
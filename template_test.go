@@ -0,0 +1,82 @@
+package main
+
+import "testing"
+
+func TestRenderTemplate(t *testing.T) {
+	fields := TemplateFields{
+		"track": "3", "artist": "Kora", "title": "night drive", "albumartist": "Kora",
+		"bpm": "124", "album": "", "disc": "0",
+	}
+	for _, tc := range []struct {
+		pattern string
+		fields  TemplateFields
+		want    string
+	}{
+		{"", fields, "03. Kora - night drive.mp3"},
+		{"Track. Title", fields, "03. night drive.mp3"},
+		{"", TemplateFields{"disc": "2", "track": "3", "title": "Exit 9"}, "2-03. Exit 9.mp3"},
+		{"", TemplateFields{"title": "Exit 9"}, "Exit 9.mp3"},
+		{"", TemplateFields{"track": "0", "title": "Exit 9"}, "Exit 9.mp3"},
+		{"{artist|upper} - {title|title}", fields, "KORA - Night Drive.mp3"},
+		{"{title|upper|sentence}", fields, "Night drive.mp3"},
+		{"{title|title}", TemplateFields{"title": "don't stop (the music)"}, "Don't Stop (The Music).mp3"},
+		{"{title}{bpm?( ({bpm} bpm))}", fields, "night drive (124 bpm).mp3"},
+		{"{title}{bpm?( ({bpm} bpm))}", TemplateFields{"title": "Exit 9"}, "Exit 9.mp3"},
+		{"{{{track:02}}} {title}", fields, "{03} night drive.mp3"},
+		{"{ARTIST} -  {catno} - {title}", fields, "Kora - - night drive.mp3"},
+		// Empty folder levels disappear; a "/" in a value is not a folder.
+		{"{albumartist}/{album}/{track:02} {title}", fields, "Kora/03 night drive.mp3"},
+		{`{artist}\{title}`, TemplateFields{"artist": "AC/DC", "title": "T.N.T."}, "AC-DC/T.N.T..mp3"},
+	} {
+		tmpl, err := resolveRenameTemplate(tc.pattern)
+		if err != nil {
+			t.Errorf("%q: %v", tc.pattern, err)
+			continue
+		}
+		if got := tmpl.Render(tc.fields, ".mp3"); got != tc.want {
+			t.Errorf("%q: got %q, want %q", tc.pattern, got, tc.want)
+		}
+	}
+}
+
+func TestRenderTemplateDiscs(t *testing.T) {
+	local := LocalTrack{TagTrack: 1, TagDisc: 1, TagDiscTotal: 2, TagArtist: "Kora", TagTitle: "Tunnel Lights"}
+	for pattern, want := range map[string]string{
+		"": "1-01. Kora - Tunnel Lights.flac",
+		// Legacy names render as they did before discs were known.
+		"Track. Artist - Title": "01. Kora - Tunnel Lights.flac",
+		"Track. Title":          "01. Tunnel Lights.flac",
+	} {
+		tmpl, err := resolveRenameTemplate(pattern)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := tmpl.Render(localTemplateFields(local), ".flac"); got != want {
+			t.Errorf("%q: got %q, want %q", pattern, got, want)
+		}
+	}
+
+	// A single disc gets no prefix, even when tagged 1/1.
+	local.TagDiscTotal = 1
+	tmpl, _ := resolveRenameTemplate("")
+	if got := tmpl.Render(localTemplateFields(local), ".flac"); got != "01. Kora - Tunnel Lights.flac" {
+		t.Errorf("single disc: got %q", got)
+	}
+}
+
+func TestParseRenameTemplateErrors(t *testing.T) {
+	for _, pattern := range []string{
+		"{tracknumber}",
+		"{title|shout}",
+		"{track:x}",
+		"{title",
+		"title}",
+		"{bpm?x}",
+		"{bpm?( {bpm}",
+		"{bpm?( {bpm})",
+	} {
+		if _, err := parseRenameTemplate(pattern); err == nil {
+			t.Errorf("%q: expected an error", pattern)
+		}
+	}
+}
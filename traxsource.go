@@ -0,0 +1,82 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	neturl "net/url"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+// Traxsource release pages live at /title/<id>/<slug>. The track list is
+// server-rendered HTML, one .trk-row per track.
+
+type traxsourceProvider struct{}
+
+func (traxsourceProvider) Name() string { return "Traxsource" }
+
+func (traxsourceProvider) CanHandle(url string) bool {
+	return hostIs(urlHost(url), "traxsource.com")
+}
+
+func (p traxsourceProvider) Fetch(ctx context.Context, url string) (*AlbumData, error) {
+	doc, err := fetchDocument(ctx, url)
+	if err != nil {
+		return nil, err
+	}
+	return p.parse(doc)
+}
+
+func (p traxsourceProvider) parse(doc *goquery.Document) (*AlbumData, error) {
+	album := &AlbumData{Source: p.Name()}
+	info := doc.Find(".ttl-info, .t-info").First()
+	album.Title = cleanText(info.Find("h1").First().Text())
+	album.Artist = joinLinkTexts(info.Find(".com-artists a"))
+	album.Label = cleanText(info.Find(".com-label, .com-labels a").First().Text())
+	if catRelease := cleanText(info.Find(".cat-rdate").First().Text()); catRelease != "" {
+		// "CAT123 | 2024-01-05"
+		album.CatalogNumber = strings.TrimSpace(strings.SplitN(catRelease, "|", 2)[0])
+	}
+
+	doc.Find(".trk-row").Each(func(_ int, row *goquery.Selection) {
+		titleCell := row.Find(".trk-cell.title").First()
+		title := cleanText(titleCell.Find("a").First().Text())
+		if title == "" {
+			return
+		}
+		version := titleCell.Find(".version").First()
+		duration := parseDurationString(strings.Trim(cleanText(version.Find(".duration").Text()), "()"))
+		version.Find(".duration").Remove()
+		trackNum := parseIntFromAny(cleanText(row.Find(".trk-cell.tnum").First().Text()))
+		album.Tracks = append(album.Tracks, AlbumTrack{
+			Title:            withMixName(title, cleanText(version.Text())),
+			Artist:           joinLinkTexts(row.Find(".trk-cell.artists a")),
+			TrackNum:         max(trackNum, len(album.Tracks)+1),
+			TrackNumExplicit: trackNum > 0,
+			Duration:         duration,
+		})
+	})
+
+	if len(album.Tracks) == 0 {
+		tracks, title, artist := parseJSONLDRelease(doc)
+		if len(tracks) == 0 {
+			return nil, fmt.Errorf("could not find Traxsource track data on page")
+		}
+		album.Tracks = tracks
+		album.Title = firstNonEmpty(album.Title, title)
+		album.Artist = firstNonEmpty(album.Artist, artist)
+	}
+	if album.Title == "" {
+		album.Title = cleanText(doc.Find("meta[property='og:title']").AttrOr("content", ""))
+	}
+	return album, nil
+}
+
+func (p traxsourceProvider) Search(ctx context.Context, query string) ([]ReleaseSummary, error) {
+	doc, err := fetchDocument(ctx, "https://www.traxsource.com/search/titles?term="+neturl.QueryEscape(query))
+	if err != nil {
+		return nil, err
+	}
+	return releaseLinks(doc, p.Name(), "https://www.traxsource.com", "/title/"), nil
+}
@@ -0,0 +1,21 @@
+package main
+
+import "testing"
+
+func TestTraxsourceParse(t *testing.T) {
+	album, err := traxsourceProvider{}.parse(loadHTMLFixture(t, "traxsource/release.html"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if album.Title != "Harbour Lights EP" || album.Artist != "Marlow, Ina Vale" {
+		t.Errorf("album = %q by %q", album.Title, album.Artist)
+	}
+	if album.Label != "Coastline Recordings" || album.CatalogNumber != "COAST020" {
+		t.Errorf("label = %q %q", album.Label, album.CatalogNumber)
+	}
+	checkTracks(t, album, []AlbumTrack{
+		{Title: "Harbour Lights (Extended Mix)", Artist: "Marlow, Ina Vale", TrackNum: 1, Duration: 372},
+		{Title: "Salt (Original Mix)", Artist: "Marlow", TrackNum: 2, Duration: 340},
+		{Title: "Harbour Lights (Sam Ortega Dub)", Artist: "Marlow, Ina Vale", TrackNum: 3, Duration: 423},
+	})
+}